@@ -28,6 +28,11 @@ func main() {
 	cfg := config.New()
 	dbCfg := config.NewDBConfig()
 	amCfg := config.NewFirebaseConfig()
+	gameCfg := config.NewGameConfig()
+	cpuCfg := config.NewCPUConfig()
+	timerCfg := config.NewTimerConfig()
+	matchingCfg := config.NewMatchmakingConfig()
+	reaperCfg := config.NewReaperConfig()
 
 	// middleware
 	authMiddleware := myMiddleware.NewAuthController(context.Background(), amCfg)
@@ -60,16 +65,32 @@ func main() {
 	roomRepository := infra.NewRoomRepository(db)
 	gameRepository := infra.NewGameRepository(redis)
 	otpRepository := infra.NewOTPRepository(redis)
+	sessionRepository := infra.NewSessionRepository(redis)
+	replayRepository := infra.NewReplayRepository(redis)
+	leaderboardRepository := infra.NewLeaderboardRepository(redis)
+	matchmakingRepository := infra.NewMatchmakingRepository(redis)
 	problemRepository := infra.NewProblemRepository(db)
+	matchRepository := infra.NewMatchRepository(db)
+	statsRepository := infra.NewStatsRepository(db)
+	ratingRepository := infra.NewRatingRepository(db)
+	cleanupRepository := infra.NewCleanupRepository(db)
 	publisher := infra.NewPublisher(redis)
 	subscriber := infra.NewSubscriber(redis)
 	msgSender := infra.NewMsgSender()
+	timer := infra.NewTimer(timerCfg)
 
 	// Init router
-	gm := usecase.NewGameManager(publisher, subscriber, gameRepository, roomRepository, problemRepository, msgSender)
+	gm := usecase.NewGameManager(publisher, subscriber, gameRepository, roomRepository, problemRepository, msgSender, sessionRepository, replayRepository, matchRepository, statsRepository, ratingRepository, leaderboardRepository, timer, gameCfg, cpuCfg)
+	matchmaker := usecase.NewMatchmaker(matchmakingRepository, roomRepository, gameRepository, otpRepository, ratingRepository, matchingCfg)
+	reaper := usecase.NewReaper(publisher, roomRepository, gameRepository, cleanupRepository, reaperCfg)
 	wsHandler := handler.NewWSHandler(gm, msgSender.(*infra.MsgSender))
-	roomHandler := handler.NewRoomHandler(wsHandler, roomRepository, otpRepository, gameRepository)
-	otpHandler := handler.NewOTPHandler(otpRepository, authMiddleware)
+	roomHandler := handler.NewRoomHandler(wsHandler, roomRepository, otpRepository, gameRepository, sessionRepository, matchmakingRepository)
+	otpHandler := handler.NewOTPHandler(otpRepository, roomRepository, authMiddleware)
+	replayHandler := handler.NewReplayHandler(replayRepository, otpRepository)
+	gameHandler := handler.NewGameHandler(matchRepository)
+	userHandler := handler.NewUserHandler(statsRepository, ratingRepository)
+	leaderboardHandler := handler.NewLeaderboardHandler(leaderboardRepository)
+	matchingHandler := handler.NewMatchingHandler(matchmaker, authMiddleware)
 
 	// debug handler
 	debugHandler := handler.NewDebugHandler(publisher)
@@ -79,10 +100,22 @@ func main() {
 
 	// start subscriber
 	go wsHandler.SubscribeHandle(context.Background(), "game")
+	go wsHandler.SubscribeTimerHandle(context.Background())
+
+	// start matchmaking
+	go matchmaker.Run(context.Background())
+
+	// start reaper
+	go reaper.Run(context.Background())
 
 	// Init router
 	router.InitRoomRouter(g, roomHandler, authMiddleware)
+	router.InitMatchingRouter(g, matchingHandler, authMiddleware)
 	router.InitOTPRouter(g, otpHandler, authMiddleware)
+	router.InitReplayRouter(g, replayHandler, authMiddleware)
+	router.InitGameRouter(g, gameHandler, authMiddleware)
+	router.InitUserRouter(g, userHandler, authMiddleware)
+	router.InitLeaderboardRouter(g, leaderboardHandler, authMiddleware)
 
 	// Graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,12 +13,15 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/redis/go-redis/v9"
+
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/config"
+	myRedis "github.com/Simo-C3/stego2-server/pkg/redis"
 )
 
 type Timer struct {
-	ID     string
-	Ticker *time.Ticker
-	Done   chan bool
+	ID   string
+	Done chan bool
 }
 
 var (
@@ -28,10 +32,11 @@ var (
 )
 
 func main() {
-	redisClient = redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-		DB:   0,
-	})
+	var err error
+	redisClient, err = myRedis.New(config.NewRedisConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	e := echo.New()
 	e.Use(middleware.Logger())
@@ -43,6 +48,8 @@ func main() {
 	e.Logger.Fatal(e.Start(":50000"))
 }
 
+// startGame は duration 秒のタイマーを開始し、1秒ごとに残り時間を通知する
+// delay を指定すると、その秒数だけ待ってからカウントを始める
 func startGame(c echo.Context) error {
 	gameID := c.QueryParam("game")
 	duration := c.QueryParam("duration")
@@ -53,9 +60,16 @@ func startGame(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "duration is required")
 	}
 	n, err := strconv.Atoi(duration)
-	if err != nil {
+	if err != nil || n <= 0 {
 		return c.String(http.StatusBadRequest, "invalid duration")
 	}
+	delay := 0
+	if d := c.QueryParam("delay"); d != "" {
+		delay, err = strconv.Atoi(d)
+		if err != nil || delay < 0 {
+			return c.String(http.StatusBadRequest, "invalid delay")
+		}
+	}
 
 	mu.Lock()
 	defer mu.Unlock()
@@ -64,24 +78,38 @@ func startGame(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "timer already exists for this game")
 	}
 
-	ticker := time.NewTicker(time.Duration(n) * time.Second)
 	done := make(chan bool)
-	timers[gameID] = &Timer{
-		ID:     gameID,
-		Ticker: ticker,
-		Done:   done,
+	timer := &Timer{
+		ID:   gameID,
+		Done: done,
 	}
+	timers[gameID] = timer
 
 	go func() {
-		for {
+		defer func() {
+			mu.Lock()
+			if timers[gameID] == timer {
+				delete(timers, gameID)
+			}
+			mu.Unlock()
+		}()
+
+		select {
+		case <-done:
+			return
+		case <-time.After(time.Duration(delay) * time.Second):
+		}
+		// 待っている間の tick が残らないように、待ち終わってから作る
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		notify(gameID, n)
+		for remaining := n - 1; remaining >= 0; remaining-- {
 			select {
 			case <-done:
 				return
 			case <-ticker.C:
-				err := redisClient.Publish(ctx, "game_notifications", fmt.Sprintf("Game %s: 30 seconds passed", gameID)).Err()
-				if err != nil {
-					log.Printf("Failed to publish message: %v", err)
-				}
+				notify(gameID, remaining)
 			}
 		}
 	}()
@@ -89,6 +117,21 @@ func startGame(c echo.Context) error {
 	return c.String(http.StatusOK, fmt.Sprintf("Timer started for game %s", gameID))
 }
 
+func notify(gameID string, remaining int) {
+	msg, err := json.Marshal(&schema.TimerNotification{
+		GameID:    gameID,
+		Remaining: remaining,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+
+	if err := redisClient.Publish(ctx, schema.TopicTimer, msg).Err(); err != nil {
+		log.Printf("Failed to publish message: %v", err)
+	}
+}
+
 func endGame(c echo.Context) error {
 	gameID := c.QueryParam("game")
 	if gameID == "" {
@@ -103,8 +146,7 @@ func endGame(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "no timer found for this game")
 	}
 
-	timer.Ticker.Stop()
-	timer.Done <- true
+	close(timer.Done)
 	delete(timers, gameID)
 
 	return c.String(http.StatusOK, fmt.Sprintf("Timer stopped for game %s", gameID))
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+type CleanupKind string
+
+const (
+	// ゲームが期限切れになった、または放置されたルームを閉じた
+	CleanupKindRoomClosed CleanupKind = "room_closed"
+	// ルームの状態をゲームの状態に合わせた
+	CleanupKindRoomSynced CleanupKind = "room_synced"
+	// どのゲームにも参加していないユーザーを消した
+	CleanupKindUserRemoved CleanupKind = "user_removed"
+)
+
+// Cleanup は reaper が片付けたものの記録
+type Cleanup struct {
+	Kind      CleanupKind
+	TargetID  string
+	Detail    string
+	CreatedAt time.Time
+}
+
+func NewCleanup(kind CleanupKind, targetID, detail string, now time.Time) *Cleanup {
+	return &Cleanup{
+		Kind:      kind,
+		TargetID:  targetID,
+		Detail:    detail,
+		CreatedAt: now,
+	}
+}
+
+// IsAbandoned は CPU 以外の全員が age より長く切断しているかを返す
+// CPU しかいない、または誰もいない場合も放置されているとみなす
+func (g *Game) IsAbandoned(now time.Time, age time.Duration) bool {
+	for _, u := range g.Users {
+		if u.IsCPU {
+			continue
+		}
+		if u.CanReconnect(now, age) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,9 +2,14 @@ package model
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/gob"
-	"log"
+	"math/rand"
+	"slices"
 	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Simo-C3/stego2-server/pkg/otp"
 	"github.com/pkg/errors"
@@ -19,6 +24,19 @@ type Room struct {
 	MaxUserNum int
 	UseCPU     bool
 	Status     string
+	Duration   int // 制限時間 (sec), 0 なら無制限
+	TeamNum    int // チーム数, 0 なら個人戦
+
+	WinCondition string
+	WinTarget    int // first_to_n の N, top_k の K
+
+	AutoStart bool // 全員が準備完了したら自動で開始する
+
+	Private      bool   // 一覧とマッチングに出さず、招待コードで参加する
+	InviteCode   string // 非公開ルームの招待コード
+	PasswordHash string // パスワード付きルームのパスワードの bcrypt ハッシュ
+
+	CreatedAt time.Time
 }
 
 type Sequence struct {
@@ -28,11 +46,22 @@ type Sequence struct {
 }
 
 type Game struct {
-	ID       string
-	Users    map[string]*User
-	Status   GameStatus
-	BaseRoom *Room
-	StartAt  int
+	ID         string
+	MatchID    string // 試合ごとの ID (リプレイの ID)
+	Users      map[string]*User
+	Spectators []string
+	Status     GameStatus
+	BaseRoom   *Room
+	StartAt    int
+	// 開始の合図の時刻に最初の問題を配り終えたか
+	Revealed bool
+
+	// 進行中の自動開始のカウントダウン
+	CountdownID string
+	// 追放されたユーザー
+	BannedUsers []string
+	// 再戦に投票したユーザー
+	RematchVotes []string
 }
 
 func (g *Game) MarshalBinary() ([]byte, error) {
@@ -57,6 +86,114 @@ type User struct {
 	Streak      int
 	DeadAt      int
 	Difficult   int
+	IsCPU       bool
+	DamageDealt int
+	Team        int // 所属チーム (1 始まり), 0 なら未所属
+	Completed   int // 完了したシーケンスの数
+	Score       int
+
+	DamageReceived int // 受けたダメージ (難易度の上昇量)
+
+	// タイピングの成績
+	TypedChars  int // 正しく入力した文字数
+	MaxPos      int // 入力中のシーケンスで到達した最も先の Pos
+	Mistypes    int
+	LevelCounts map[int]int // 完了したシーケンスのレベルごとの数
+
+	Ready  bool
+	Rating float64 // 参加したときのレーティング
+
+	// 接続の状態
+	ConnID         string
+	DisconnectedAt int64 // unix milli, 0 なら接続中
+	ReconnectToken string
+	JoinedAt       int64 // unix milli, 最初に接続した時刻
+
+	// 攻撃対象の選び方
+	TargetMode     TargetMode
+	TargetUserID   string
+	LastAttackedBy string
+
+	// 特殊シーケンスの効果
+	Shield       bool
+	Reflect      bool
+	DoubleDamage bool
+	FrozenUntil  int64 // unix milli
+}
+
+// CurrentSequence は入力中のシーケンスを返す
+func (u *User) CurrentSequence() (*Sequence, error) {
+	if len(u.Sequences) == 0 {
+		return nil, ErrNoSequence
+	}
+	return u.Sequences[0], nil
+}
+
+// Type は入力済みの文字列を検証して Pos を進める
+// 入力がシーケンスの先頭と一致しない場合は ErrMistype を返し、Pos は変更しない
+// シーケンスを最後まで入力し終えた場合は true を返す
+func (u *User) Type(input string) (bool, error) {
+	seq, err := u.CurrentSequence()
+	if err != nil {
+		return false, err
+	}
+
+	if !strings.HasPrefix(seq.Value, input) {
+		return false, ErrMistype
+	}
+
+	// 消してから打ち直した文字は数えない
+	if len(input) > u.MaxPos {
+		u.TypedChars += utf8.RuneCountInString(input[u.MaxPos:])
+		u.MaxPos = len(input)
+	}
+	u.Pos = len(input)
+	return u.Pos == len(seq.Value), nil
+}
+
+// IsConnected は接続中かどうかを返す
+func (u *User) IsConnected() bool {
+	return u.IsCPU || u.DisconnectedAt == 0
+}
+
+// CanReconnect は切断から猶予の時間内かどうかを返す
+func (u *User) CanReconnect(now time.Time, grace time.Duration) bool {
+	return u.IsConnected() || now.Sub(time.UnixMilli(u.DisconnectedAt)) <= grace
+}
+
+// IsFrozen は入力が止められているかを返す
+func (u *User) IsFrozen(now time.Time) bool {
+	return now.UnixMilli() < u.FrozenUntil
+}
+
+// Multiplier はストリークに応じた攻撃力の倍率を返す
+// 到達した閾値の数だけ倍率が 1 ずつ上がる
+func (u *User) Multiplier(thresholds []int) int {
+	m := 1
+	for _, t := range thresholds {
+		if u.Streak >= t {
+			m++
+		}
+	}
+	return m
+}
+
+// InputSeq は入力済みの文字列を返す
+func (u *User) InputSeq() string {
+	seq, err := u.CurrentSequence()
+	if err != nil {
+		return ""
+	}
+	return seq.Value[:u.Pos]
+}
+
+// CurrentValue は入力中のシーケンスの文字列を返す
+func (u *User) CurrentValue() string {
+	seq, err := u.CurrentSequence()
+	if err != nil {
+		return ""
+	}
+	return seq.Value
 }
 
 type Problem struct {
@@ -69,13 +206,23 @@ type GameResult struct {
 	UserID      string
 	DisplayName string
 	Rank        int
+	Team        int
+	TeamRank    int
+}
+
+// Standing は勝敗を決める順位を返す。チーム戦ならチームの順位
+func (r *GameResult) Standing() int {
+	if r.TeamRank > 0 {
+		return r.TeamRank
+	}
+	return r.Rank
 }
 
 type OTP struct {
 	OTP string
 }
 
-func NewRoom(id, ownerID, name, hostName string, minUserNum, maxUserNum int, useCPU bool, status string) *Room {
+func NewRoom(id, ownerID, name, hostName string, minUserNum, maxUserNum int, useCPU bool, status string, duration, teamNum int, winCondition string, winTarget int, autoStart bool) *Room {
 	return &Room{
 		ID:         id,
 		OwnerID:    ownerID,
@@ -85,6 +232,13 @@ func NewRoom(id, ownerID, name, hostName string, minUserNum, maxUserNum int, use
 		MaxUserNum: maxUserNum,
 		UseCPU:     useCPU,
 		Status:     status,
+		Duration:   duration,
+		TeamNum:    teamNum,
+
+		WinCondition: winCondition,
+		WinTarget:    winTarget,
+
+		AutoStart: autoStart,
 	}
 }
 
@@ -105,6 +259,15 @@ func NewUser(id, displayName string) *User {
 	}
 }
 
+func NewCPUUser(id, displayName string) *User {
+	return &User{
+		ID:          id,
+		DisplayName: displayName,
+		Life:        InitUserLife,
+		IsCPU:       true,
+	}
+}
+
 func NewOTP() (*OTP, error) {
 	otp, err := otp.GenerateOTP(32)
 
@@ -126,79 +289,161 @@ func NewProblem(id int, collectSentence string, level int) *Problem {
 }
 
 func (g *Game) AddUser(user *User) error {
+	if g.IsBanned(user.ID) {
+		return ErrBanned
+	}
 	if g.Status != GameStatusPending {
-		if func() bool {
-			for _, u := range g.Users {
-				if u.ID == user.ID {
-					return true
-				}
-			}
-			return false
-		}() {
+		if _, ok := g.Users[user.ID]; ok {
 			return nil
 		}
 		return ErrGameIsStarted
 	}
+	if slices.Contains(g.Spectators, user.ID) {
+		return ErrAlreadyJoined
+	}
+	if _, ok := g.Users[user.ID]; !ok && g.BaseRoom != nil && g.BaseRoom.MaxUserNum > 0 && len(g.Users) >= g.BaseRoom.MaxUserNum {
+		return ErrMaxUserNum
+	}
 
 	g.Users[user.ID] = user
 	return nil
 }
 
+// HasStarted は開始の合図の時刻を過ぎてゲームが進行中かどうかを返す
+func (g *Game) HasStarted(now time.Time) bool {
+	return g.Status == GameStatusPlaying && now.Unix() >= int64(g.StartAt)
+}
+
+// AddSpectator は観戦者を追加する
+// 観戦者は Users に含まれず、MaxUserNum にも数えない
+func (g *Game) AddSpectator(userID string) error {
+	if g.IsBanned(userID) {
+		return ErrBanned
+	}
+	if _, ok := g.Users[userID]; ok {
+		return ErrAlreadyJoined
+	}
+	if !slices.Contains(g.Spectators, userID) {
+		g.Spectators = append(g.Spectators, userID)
+	}
+	return nil
+}
+
+// RemoveSpectator は観戦者を取り除く
+func (g *Game) RemoveSpectator(userID string) {
+	g.Spectators = slices.DeleteFunc(g.Spectators, func(id string) bool {
+		return id == userID
+	})
+}
+
+// SelectTarget は攻撃者の TargetMode に従って攻撃対象を選ぶ
+// 指定した相手が攻撃できない場合はランダムに選ぶ
+// 攻撃できる相手がいない場合は nil を返す
+func (g *Game) SelectTarget(attacker *User) *User {
+	candidates := make([]*User, 0, len(g.Users))
+	for _, u := range g.Users {
+		// 自分以外でライフが残っているユーザーを攻撃対象にする
+		if u.Life > 0 && u.ID != attacker.ID && !g.IsTeammate(u, attacker) {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// map の順序に依存しないように並べておく
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ID < candidates[j].ID
+	})
+
+	find := func(id string) *User {
+		for _, u := range candidates {
+			if u.ID == id {
+				return u
+			}
+		}
+		return nil
+	}
+
+	switch attacker.TargetMode {
+	case TargetModeLeader:
+		// ライフが多く、難易度が低いほど優勢
+		return slices.MinFunc(candidates, func(a, b *User) int {
+			return cmp.Or(cmp.Compare(b.Life, a.Life), cmp.Compare(a.Difficult, b.Difficult))
+		})
+	case TargetModeWeakest:
+		return slices.MinFunc(candidates, func(a, b *User) int {
+			return cmp.Or(cmp.Compare(a.Life, b.Life), cmp.Compare(b.Difficult, a.Difficult))
+		})
+	case TargetModeRevenge:
+		if u := find(attacker.LastAttackedBy); u != nil {
+			return u
+		}
+	case TargetModeUser:
+		if u := find(attacker.TargetUserID); u != nil {
+			return u
+		}
+	}
+
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// AliveUserNum は生存者の数を返す
+func (g *Game) AliveUserNum() int {
+	n := 0
+	for _, u := range g.Users {
+		if u.Life > 0 {
+			n++
+		}
+	}
+	return n
+}
+
 func (g *Game) GetRanking(userID string) (int, error) {
-	log.Println("GetRanking")
 	deadUsers := make([]*User, 0, len(g.Users))
 	for _, user := range g.Users {
-		log.Println("[145] user: ", user)
 		if user.Life <= 0 {
-			log.Println("[147] user.Life: ", user.Life)
 			deadUsers = append(deadUsers, user)
 		}
 	}
 
 	num := len(g.Users)
-	log.Println("[153] num: ", num)
 
 	sort.Slice(deadUsers, func(i, j int) bool {
 		return deadUsers[i].DeadAt < deadUsers[j].DeadAt
 	})
-	log.Printf("[158] deadUsers: %+v\n", deadUsers)
 
 	for rank, user := range deadUsers {
-		log.Println("[161] rank: ", rank)
-		log.Println("[167] user.ID: ", user.ID)
-		log.Printf("[168] user: %+v\n", user)
 		if user.ID == userID {
-			log.Println("[170] rank: ", rank)
 			return num - rank, nil
 		}
 	}
 
-	return 1000, errors.New("user not found")
+	return 0, errors.New("user not found")
 }
 
+// GetResult は終了条件に従って順位を計算する
 func (g *Game) GetResult() ([]*GameResult, error) {
 	users := make([]*User, 0, len(g.Users))
 	for _, user := range g.Users {
 		users = append(users, user)
 	}
 
-	sort.Slice(users, func(i, j int) bool {
-		// DeadAtが初期値のものは1位 (DeadAtが大きいほど上位)
-		if users[i].DeadAt == 0 {
-			return true
-		}
-		if users[j].DeadAt == 0 {
-			return false
-		}
-		return users[i].DeadAt > users[j].DeadAt
+	cond := g.WinCondition()
+	sort.SliceStable(users, func(i, j int) bool {
+		return cond.Compare(users[i], users[j]) < 0
 	})
 
+	teamRanks := g.teamRanks(users)
+
 	res := make([]*GameResult, 0, len(users))
 	for i, user := range users {
 		res = append(res, &GameResult{
 			UserID:      user.ID,
 			DisplayName: user.DisplayName,
 			Rank:        i + 1,
+			Team:        user.Team,
+			TeamRank:    teamRanks[user.Team],
 		})
 	}
 
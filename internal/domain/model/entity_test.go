@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUserType(t *testing.T) {
+	tests := []struct {
+		name string
+		// 順に送る入力。最後の入力の結果を確かめる
+		inputs     []string
+		wantDone   bool
+		wantErr    error
+		wantPos    int
+		wantTyped  int
+		wantMaxPos int
+	}{
+		{
+			name:       "prefix",
+			inputs:     []string{"he"},
+			wantPos:    2,
+			wantTyped:  2,
+			wantMaxPos: 2,
+		},
+		{
+			name:       "complete",
+			inputs:     []string{"h", "hel", "hello"},
+			wantDone:   true,
+			wantPos:    5,
+			wantTyped:  5,
+			wantMaxPos: 5,
+		},
+		{
+			name:       "mistype keeps pos",
+			inputs:     []string{"hel", "hex"},
+			wantErr:    ErrMistype,
+			wantPos:    3,
+			wantTyped:  3,
+			wantMaxPos: 3,
+		},
+		{
+			name:       "backspace does not recount",
+			inputs:     []string{"hell", "he", "hell"},
+			wantPos:    4,
+			wantTyped:  4,
+			wantMaxPos: 4,
+		},
+		{
+			name:       "retyped beyond max pos",
+			inputs:     []string{"hel", "h", "hello"},
+			wantDone:   true,
+			wantPos:    5,
+			wantTyped:  5,
+			wantMaxPos: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &User{Sequences: []*Sequence{{Value: "hello"}}}
+			var done bool
+			var err error
+			for _, input := range tt.inputs {
+				done, err = u.Type(input)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if done != tt.wantDone {
+				t.Errorf("done = %v, want %v", done, tt.wantDone)
+			}
+			if u.Pos != tt.wantPos || u.TypedChars != tt.wantTyped || u.MaxPos != tt.wantMaxPos {
+				t.Errorf("Pos, TypedChars, MaxPos = %d, %d, %d, want %d, %d, %d", u.Pos, u.TypedChars, u.MaxPos, tt.wantPos, tt.wantTyped, tt.wantMaxPos)
+			}
+		})
+	}
+}
+
+func TestUserTypeWithoutSequence(t *testing.T) {
+	u := &User{}
+	if _, err := u.Type("a"); !errors.Is(err, ErrNoSequence) {
+		t.Errorf("err = %v, want %v", err, ErrNoSequence)
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type LeaderboardKind string
+
+const (
+	LeaderboardKindWins   LeaderboardKind = "wins"
+	LeaderboardKindWPM    LeaderboardKind = "wpm"
+	LeaderboardKindRating LeaderboardKind = "rating"
+)
+
+type LeaderboardPeriod string
+
+const (
+	LeaderboardPeriodAll    LeaderboardPeriod = "all"
+	LeaderboardPeriodWeekly LeaderboardPeriod = "weekly"
+	LeaderboardPeriodDaily  LeaderboardPeriod = "daily"
+)
+
+var ErrInvalidLeaderboard = errors.New("invalid leaderboard")
+
+// LeaderboardPeriods は全ての集計期間
+var LeaderboardPeriods = []LeaderboardPeriod{
+	LeaderboardPeriodAll,
+	LeaderboardPeriodWeekly,
+	LeaderboardPeriodDaily,
+}
+
+func NewLeaderboardKind(kind string) (LeaderboardKind, error) {
+	switch k := LeaderboardKind(kind); k {
+	case LeaderboardKindWins, LeaderboardKindWPM, LeaderboardKindRating:
+		return k, nil
+	}
+	return "", ErrInvalidLeaderboard
+}
+
+// NewLeaderboardPeriod は空文字のとき all を返す
+func NewLeaderboardPeriod(period string) (LeaderboardPeriod, error) {
+	switch p := LeaderboardPeriod(period); p {
+	case "":
+		return LeaderboardPeriodAll, nil
+	case LeaderboardPeriodAll, LeaderboardPeriodWeekly, LeaderboardPeriodDaily:
+		return p, nil
+	}
+	return "", ErrInvalidLeaderboard
+}
+
+// Window は now を含む集計期間の名前と終わりの時刻を返す
+// 期間ごとに別のランキングを使うことで、期間が変わると自動で新しいランキングになる
+// all の場合は終わりの時刻はゼロ値になる
+func (p LeaderboardPeriod) Window(now time.Time) (string, time.Time) {
+	switch p {
+	case LeaderboardPeriodWeekly:
+		year, week := now.ISOWeek()
+		// 月曜日の 0 時から1週間
+		offset := (int(now.Weekday()) + 6) % 7
+		start := time.Date(now.Year(), now.Month(), now.Day()-offset, 0, 0, 0, 0, now.Location())
+		return fmt.Sprintf("%d-W%02d", year, week), start.AddDate(0, 0, 7)
+	case LeaderboardPeriodDaily:
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		return start.Format("20060102"), start.AddDate(0, 0, 1)
+	}
+	return "", time.Time{}
+}
+
+type LeaderboardEntry struct {
+	UserID      string
+	DisplayName string
+	Score       float64
+	Rank        int // 1 始まり
+}
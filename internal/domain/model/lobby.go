@@ -0,0 +1,31 @@
+package model
+
+// ReadyUserNum は準備完了のユーザーの数を返す
+func (g *Game) ReadyUserNum() int {
+	n := 0
+	for _, u := range g.Users {
+		if u.Ready || u.IsCPU {
+			n++
+		}
+	}
+	return n
+}
+
+// AllReady は全員が準備完了かどうかを返す
+func (g *Game) AllReady() bool {
+	return len(g.Users) > 0 && g.ReadyUserNum() == len(g.Users)
+}
+
+// CanStart は MinUserNum 人が準備完了しているかを返す
+// CPU を使うルームでは、CPU で埋まる空席も準備完了として数える
+func (g *Game) CanStart() error {
+	required := max(1, g.BaseRoom.MinUserNum)
+	ready := g.ReadyUserNum()
+	if g.BaseRoom.UseCPU {
+		ready += max(0, g.BaseRoom.MinUserNum-len(g.Users))
+	}
+	if ready < required {
+		return ErrNotEnoughReady
+	}
+	return nil
+}
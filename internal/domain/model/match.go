@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+// MatchRecord は終了した試合の記録
+type MatchRecord struct {
+	ID           string // MatchID
+	RoomID       string
+	RoomName     string
+	WinCondition string
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	Results      []*MatchResult
+}
+
+// MatchResult は試合でのユーザーごとの成績
+type MatchResult struct {
+	UserID         string
+	DisplayName    string
+	IsCPU          bool
+	Rank           int
+	Team           int
+	TeamRank       int
+	DeadAt         int // unix sec, 0 なら生存
+	Completed      int
+	DamageDealt    int
+	DamageReceived int
+}
+
+// NewMatchRecord は終了したゲームと順位から試合の記録を作る
+func NewMatchRecord(g *Game, results []*GameResult, finishedAt time.Time) *MatchRecord {
+	record := &MatchRecord{
+		ID:           g.MatchID,
+		RoomID:       g.ID,
+		RoomName:     g.BaseRoom.Name,
+		WinCondition: g.BaseRoom.WinCondition,
+		StartedAt:    time.Unix(int64(g.StartAt), 0),
+		FinishedAt:   finishedAt,
+		Results:      make([]*MatchResult, 0, len(results)),
+	}
+	for _, r := range results {
+		u, ok := g.Users[r.UserID]
+		if !ok {
+			continue
+		}
+		record.Results = append(record.Results, &MatchResult{
+			UserID:         u.ID,
+			DisplayName:    u.DisplayName,
+			IsCPU:          u.IsCPU,
+			Rank:           r.Rank,
+			Team:           r.Team,
+			TeamRank:       r.TeamRank,
+			DeadAt:         u.DeadAt,
+			Completed:      u.Completed,
+			DamageDealt:    u.DamageDealt,
+			DamageReceived: u.DamageReceived,
+		})
+	}
+	return record
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// MatchTicket はマッチングの待ち行列に並んでいるユーザー
+type MatchTicket struct {
+	UserID     string  `json:"userId"`
+	Name       string  `json:"name"`
+	Rating     float64 `json:"rating"`
+	EnqueuedAt int64   `json:"enqueuedAt"` // unix milli
+	PolledAt   int64   `json:"polledAt"`   // unix milli, 最後に結果を取りに来た時刻
+}
+
+// MatchFound はマッチングの結果。OTP でそのままルームに参加できる
+type MatchFound struct {
+	RoomID string `json:"roomId"`
+	OTP    string `json:"otp"`
+}
+
+// MatchWindow はレーティングの探索幅。待ち時間に応じて広がる
+type MatchWindow struct {
+	Initial float64
+	Growth  float64 // 1秒あたりに広がる幅
+	Max     float64
+}
+
+func NewMatchTicket(userID, name string, rating float64, now time.Time) *MatchTicket {
+	return &MatchTicket{
+		UserID:     userID,
+		Name:       name,
+		Rating:     rating,
+		EnqueuedAt: now.UnixMilli(),
+		PolledAt:   now.UnixMilli(),
+	}
+}
+
+// Window は now 時点でのレーティングの探索幅を返す
+func (t *MatchTicket) Window(w *MatchWindow, now time.Time) float64 {
+	waited := now.Sub(time.UnixMilli(t.EnqueuedAt)).Seconds()
+	return min(w.Initial+w.Growth*max(0, waited), w.Max)
+}
+
+// IsStale は結果を取りに来なくなってから timeout を過ぎているか
+func (t *MatchTicket) IsStale(now time.Time, timeout time.Duration) bool {
+	return now.Sub(time.UnixMilli(t.PolledAt)) > timeout
+}
+
+// Accepts は rating が now 時点の探索幅に収まっているか
+func (t *MatchTicket) Accepts(rating float64, w *MatchWindow, now time.Time) bool {
+	return math.Abs(t.Rating-rating) <= t.Window(w, now)
+}
+
+// Matches は t と o がお互いの探索幅に収まっているか
+func (t *MatchTicket) Matches(o *MatchTicket, w *MatchWindow, now time.Time) bool {
+	return t.Accepts(o.Rating, w, now) && o.Accepts(t.Rating, w, now)
+}
+
+// GroupMatchTickets は待ち時間の長い順に、全員がお互いの探索幅に収まるユーザーを size 人までまとめる
+// minSize 人に満たないグループは作らない
+func GroupMatchTickets(tickets []*MatchTicket, w *MatchWindow, now time.Time, minSize, size int) [][]*MatchTicket {
+	waiting := slices.Clone(tickets)
+	slices.SortFunc(waiting, func(a, b *MatchTicket) int {
+		return cmp.Compare(a.EnqueuedAt, b.EnqueuedAt)
+	})
+
+	matched := make(map[string]bool, len(waiting))
+	groups := make([][]*MatchTicket, 0)
+	for _, anchor := range waiting {
+		if matched[anchor.UserID] {
+			continue
+		}
+
+		candidates := make([]*MatchTicket, 0)
+		for _, t := range waiting {
+			if t == anchor || matched[t.UserID] {
+				continue
+			}
+			if t.Matches(anchor, w, now) {
+				candidates = append(candidates, t)
+			}
+		}
+		if len(candidates)+1 < minSize {
+			continue
+		}
+
+		// レーティングが近い順に、既に入れた全員と組めるユーザーだけを入れる
+		slices.SortStableFunc(candidates, func(a, b *MatchTicket) int {
+			return cmp.Compare(math.Abs(a.Rating-anchor.Rating), math.Abs(b.Rating-anchor.Rating))
+		})
+		group := []*MatchTicket{anchor}
+		for _, t := range candidates {
+			if len(group) >= size {
+				break
+			}
+			if !slices.ContainsFunc(group, func(m *MatchTicket) bool { return !t.Matches(m, w, now) }) {
+				group = append(group, t)
+			}
+		}
+		if len(group) < minSize {
+			continue
+		}
+
+		for _, t := range group {
+			matched[t.UserID] = true
+		}
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// HasVacancy はマッチングで確保された reserved 席を除いて、新しいユーザーが入れる空きがあるか
+func (g *Game) HasVacancy(reserved int) bool {
+	if g.BaseRoom == nil || g.BaseRoom.MaxUserNum <= 0 {
+		return true
+	}
+	return len(g.Users)+reserved < g.BaseRoom.MaxUserNum
+}
+
+// AverageRating は CPU を除いたユーザーのレーティングの平均を返す
+// 人間のユーザーがいない場合は false を返す
+func (g *Game) AverageRating() (float64, bool) {
+	sum, n := 0.0, 0
+	for _, u := range g.Users {
+		if u.IsCPU {
+			continue
+		}
+		sum += u.Rating
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
@@ -0,0 +1,124 @@
+package model
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGroupMatchTickets(t *testing.T) {
+	now := time.UnixMilli(1_000_000)
+	w := &MatchWindow{Initial: 100, Growth: 10, Max: 300}
+	ticket := func(id string, rating float64, waited time.Duration) *MatchTicket {
+		return &MatchTicket{UserID: id, Rating: rating, EnqueuedAt: now.Add(-waited).UnixMilli()}
+	}
+
+	tests := []struct {
+		name    string
+		tickets []*MatchTicket
+		minSize int
+		size    int
+		want    [][]string
+	}{
+		{
+			name: "close ratings",
+			tickets: []*MatchTicket{
+				ticket("a", 1500, 3*time.Second),
+				ticket("b", 1550, 2*time.Second),
+				ticket("c", 1450, time.Second),
+			},
+			minSize: 2,
+			size:    4,
+			want:    [][]string{{"a", "b", "c"}},
+		},
+		{
+			name: "not enough users",
+			tickets: []*MatchTicket{
+				ticket("a", 1500, 0),
+				ticket("b", 1900, 0),
+			},
+			minSize: 2,
+			size:    4,
+			want:    [][]string{},
+		},
+		{
+			name: "every pair must accept each other",
+			tickets: []*MatchTicket{
+				ticket("a", 1500, 2*time.Second),
+				ticket("b", 1420, time.Second),
+				ticket("c", 1580, 0),
+			},
+			minSize: 2,
+			size:    4,
+			// b と c は 160 離れていて、お互いの探索幅に収まらない
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "window grows with waiting time",
+			tickets: []*MatchTicket{
+				ticket("a", 1500, 20*time.Second),
+				ticket("b", 1780, 20*time.Second),
+			},
+			minSize: 2,
+			size:    4,
+			want:    [][]string{{"a", "b"}},
+		},
+		{
+			name: "split by size",
+			tickets: []*MatchTicket{
+				ticket("a", 1500, 4*time.Second),
+				ticket("b", 1510, 3*time.Second),
+				ticket("c", 1520, 2*time.Second),
+				ticket("d", 1530, time.Second),
+			},
+			minSize: 2,
+			size:    2,
+			want:    [][]string{{"a", "b"}, {"c", "d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := GroupMatchTickets(tt.tickets, w, now, tt.minSize, tt.size)
+			got := make([][]string, 0, len(groups))
+			for _, g := range groups {
+				ids := make([]string, 0, len(g))
+				for _, m := range g {
+					ids = append(ids, m.UserID)
+				}
+				slices.Sort(ids)
+				got = append(got, ids)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal[[]string]) {
+				t.Errorf("GroupMatchTickets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameHasVacancy(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		users    int
+		reserved int
+		want     bool
+	}{
+		{"empty", 4, 0, 0, true},
+		{"full", 4, 4, 0, false},
+		{"reserved fills the room", 4, 2, 2, false},
+		{"reserved leaves a seat", 4, 2, 1, true},
+		{"unlimited", 0, 10, 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := make([]*User, 0, tt.users)
+			for i := range tt.users {
+				users = append(users, &User{ID: string(rune('a' + i))})
+			}
+			g := newTestGame(&Room{MaxUserNum: tt.max}, users...)
+			if got := g.HasVacancy(tt.reserved); got != tt.want {
+				t.Errorf("HasVacancy(%d) = %v, want %v", tt.reserved, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import "slices"
+
+// IsBanned は userID のユーザーがこのルームから追放されているかを返す
+func (g *Game) IsBanned(userID string) bool {
+	return slices.Contains(g.BannedUsers, userID)
+}
+
+// Kick はロビーからユーザーを取り除く
+func (g *Game) Kick(userID string) error {
+	if g.Status != GameStatusPending {
+		return ErrGameIsStarted
+	}
+	if userID == g.BaseRoom.OwnerID {
+		return ErrInvalidTarget
+	}
+	if _, ok := g.Users[userID]; !ok {
+		return ErrInvalidTarget
+	}
+
+	delete(g.Users, userID)
+	return nil
+}
+
+// Ban はロビーからユーザーを取り除き、以後の参加を拒否する
+func (g *Game) Ban(userID string) error {
+	if err := g.Kick(userID); err != nil {
+		return err
+	}
+
+	g.BannedUsers = append(g.BannedUsers, userID)
+	return nil
+}
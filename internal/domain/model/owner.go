@@ -0,0 +1,27 @@
+package model
+
+// NextOwner は次のオーナーの候補として、最も長く接続しているユーザーを返す
+// 候補がいない場合は nil を返す
+func (g *Game) NextOwner() *User {
+	var next *User
+	for _, u := range g.Users {
+		if u.ID == g.BaseRoom.OwnerID || u.IsCPU || u.JoinedAt == 0 || !u.IsConnected() {
+			continue
+		}
+		if next == nil || u.JoinedAt < next.JoinedAt || (u.JoinedAt == next.JoinedAt && u.ID < next.ID) {
+			next = u
+		}
+	}
+	return next
+}
+
+// TransferOwner はオーナーを userID のユーザーに変更する
+func (g *Game) TransferOwner(userID string) error {
+	u, ok := g.Users[userID]
+	if !ok || u.IsCPU || u.ID == g.BaseRoom.OwnerID {
+		return ErrInvalidTarget
+	}
+
+	g.BaseRoom.OwnerID = userID
+	return nil
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/Simo-C3/stego2-server/pkg/otp"
+)
+
+const inviteCodeLength = 6
+
+func NewInviteCode() (string, error) {
+	code, err := otp.GenerateCode(inviteCodeLength)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return code, nil
+}
+
+// SetPassword はパスワードをハッシュにして保存する。空文字の場合はパスワードなしにする
+func (r *Room) SetPassword(password string) error {
+	if password == "" {
+		r.PasswordHash = ""
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.PasswordHash = string(hash)
+	return nil
+}
+
+func (r *Room) HasPassword() bool {
+	return r.PasswordHash != ""
+}
+
+// CheckPassword はパスワードなしのルームでは常に nil を返す
+func (r *Room) CheckPassword(password string) error {
+	if !r.HasPassword() {
+		return nil
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(r.PasswordHash), []byte(password)); err != nil {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// IsRestricted は参加にそのルーム用の OTP が必要か
+func (r *Room) IsRestricted() bool {
+	return r.Private || r.HasPassword()
+}
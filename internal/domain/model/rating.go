@@ -0,0 +1,187 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// Glicko-2 のパラメータ
+const (
+	InitRating     = 1500.0
+	InitRD         = 350.0
+	InitVolatility = 0.06
+
+	// ボラティリティの変化のしやすさ
+	ratingTau = 0.5
+	// Glicko-2 の内部の尺度への変換係数
+	ratingScale   = 173.7178
+	ratingEpsilon = 0.000001
+)
+
+// Rating は Glicko-2 によるユーザーの強さ
+type Rating struct {
+	UserID      string
+	Rating      float64
+	RD          float64 // レーティングの信頼区間の幅
+	Volatility  float64
+	GamesPlayed int
+	UpdatedAt   time.Time
+}
+
+// RatingChange は1試合でのレーティングの変化
+type RatingChange struct {
+	UserID string
+	Before *Rating
+	After  *Rating
+}
+
+// Delta はレーティングの変化量を返す
+func (c *RatingChange) Delta() float64 {
+	return c.After.Rating - c.Before.Rating
+}
+
+func NewRating(userID string) *Rating {
+	return &Rating{
+		UserID:     userID,
+		Rating:     InitRating,
+		RD:         InitRD,
+		Volatility: InitVolatility,
+	}
+}
+
+// UpdateRatings は1試合の順位を全員との1対1の勝敗とみなして、Glicko-2 でレーティングを更新する
+// 順位が上なら勝ち、同じなら引き分けとする
+// チーム戦ではチームの順位で比べ、同じチームのユーザーとは対戦したとみなさない
+func UpdateRatings(ratings map[string]*Rating, results []*GameResult, now time.Time) []*RatingChange {
+	changes := make([]*RatingChange, 0, len(results))
+	for _, r := range results {
+		player, ok := ratings[r.UserID]
+		if !ok {
+			continue
+		}
+
+		var opponents []*Rating
+		var scores []float64
+		for _, o := range results {
+			opponent, ok := ratings[o.UserID]
+			if o.UserID == r.UserID || !ok {
+				continue
+			}
+			if r.TeamRank > 0 && r.Team == o.Team {
+				continue
+			}
+			opponents = append(opponents, opponent)
+			switch {
+			case r.Standing() < o.Standing():
+				scores = append(scores, 1)
+			case r.Standing() > o.Standing():
+				scores = append(scores, 0)
+			default:
+				scores = append(scores, 0.5)
+			}
+		}
+
+		after := player.update(opponents, scores)
+		after.UpdatedAt = now
+		changes = append(changes, &RatingChange{
+			UserID: r.UserID,
+			Before: player,
+			After:  after,
+		})
+	}
+	return changes
+}
+
+func ratingG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func ratingE(mu, muj, phij float64) float64 {
+	return 1 / (1 + math.Exp(-ratingG(phij)*(mu-muj)))
+}
+
+// update は1つの評価期間の結果で新しいレーティングを返す
+func (r *Rating) update(opponents []*Rating, scores []float64) *Rating {
+	mu := (r.Rating - InitRating) / ratingScale
+	phi := r.RD / ratingScale
+
+	next := &Rating{
+		UserID:      r.UserID,
+		Rating:      r.Rating,
+		Volatility:  r.Volatility,
+		GamesPlayed: r.GamesPlayed + 1,
+	}
+	if len(opponents) == 0 {
+		// 対戦相手がいない場合は RD だけ広がる
+		next.RD = math.Min(math.Sqrt(phi*phi+r.Volatility*r.Volatility)*ratingScale, InitRD)
+		return next
+	}
+
+	var vInv, sum float64
+	for i, o := range opponents {
+		muj := (o.Rating - InitRating) / ratingScale
+		phij := o.RD / ratingScale
+		g := ratingG(phij)
+		e := ratingE(mu, muj, phij)
+		vInv += g * g * e * (1 - e)
+		sum += g * (scores[i] - e)
+	}
+	v := 1 / vInv
+	delta := v * sum
+
+	sigma := r.newVolatility(phi, v, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*sum
+
+	next.Rating = newMu*ratingScale + InitRating
+	next.RD = newPhi * ratingScale
+	next.Volatility = sigma
+	return next
+}
+
+// newVolatility は Illinois 法で新しいボラティリティを求める
+func (r *Rating) newVolatility(phi, v, delta float64) float64 {
+	a := math.Log(r.Volatility * r.Volatility)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(ratingTau*ratingTau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*ratingTau) < 0 {
+			k++
+		}
+		B = a - k*ratingTau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > ratingEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
+
+// RatingHistory は試合ごとのレーティングの履歴
+type RatingHistory struct {
+	UserID    string
+	MatchID   string
+	Before    float64
+	After     float64
+	RD        float64
+	CreatedAt time.Time
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRatingUpdate(t *testing.T) {
+	// Glickman による Glicko-2 の解説の計算例
+	player := &Rating{UserID: "a", Rating: 1500, RD: 200, Volatility: 0.06}
+	opponents := []*Rating{
+		{UserID: "b", Rating: 1400, RD: 30, Volatility: 0.06},
+		{UserID: "c", Rating: 1550, RD: 100, Volatility: 0.06},
+		{UserID: "d", Rating: 1700, RD: 300, Volatility: 0.06},
+	}
+
+	got := player.update(opponents, []float64{1, 0, 0})
+	if math.Abs(got.Rating-1464.06) > 0.01 {
+		t.Errorf("Rating = %v, want 1464.06", got.Rating)
+	}
+	if math.Abs(got.RD-151.52) > 0.01 {
+		t.Errorf("RD = %v, want 151.52", got.RD)
+	}
+	if math.Abs(got.Volatility-0.05999) > 0.00001 {
+		t.Errorf("Volatility = %v, want 0.05999", got.Volatility)
+	}
+	if got.GamesPlayed != 1 {
+		t.Errorf("GamesPlayed = %v, want 1", got.GamesPlayed)
+	}
+}
+
+func TestRatingUpdateWithoutOpponents(t *testing.T) {
+	player := &Rating{UserID: "a", Rating: 1500, RD: 50, Volatility: 0.06}
+
+	got := player.update(nil, nil)
+	if got.Rating != 1500 {
+		t.Errorf("Rating = %v, want 1500", got.Rating)
+	}
+	if got.RD <= 50 || got.RD > InitRD {
+		t.Errorf("RD = %v, want in (50, %v]", got.RD, InitRD)
+	}
+}
+
+func TestUpdateRatings(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		results []*GameResult
+		// 上がるユーザーと下がるユーザー、変わらないユーザー
+		up, down, same []string
+	}{
+		{
+			name: "free for all",
+			results: []*GameResult{
+				{UserID: "a", Rank: 1},
+				{UserID: "b", Rank: 2},
+				{UserID: "c", Rank: 3},
+			},
+			up:   []string{"a"},
+			down: []string{"c"},
+		},
+		{
+			name: "teams compare by team rank",
+			results: []*GameResult{
+				{UserID: "a", Rank: 1, Team: 1, TeamRank: 1},
+				{UserID: "b", Rank: 2, Team: 2, TeamRank: 2},
+				{UserID: "c", Rank: 3, Team: 1, TeamRank: 1},
+				{UserID: "d", Rank: 4, Team: 2, TeamRank: 2},
+			},
+			up:   []string{"a", "c"},
+			down: []string{"b", "d"},
+		},
+		{
+			name: "same team rank is a draw",
+			results: []*GameResult{
+				{UserID: "a", Rank: 1, Team: 1, TeamRank: 1},
+				{UserID: "b", Rank: 2, Team: 2, TeamRank: 1},
+			},
+			same: []string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratings := make(map[string]*Rating, len(tt.results))
+			for _, r := range tt.results {
+				ratings[r.UserID] = NewRating(r.UserID)
+			}
+
+			changes := UpdateRatings(ratings, tt.results, now)
+			if len(changes) != len(tt.results) {
+				t.Fatalf("len(changes) = %v, want %v", len(changes), len(tt.results))
+			}
+			deltas := make(map[string]float64, len(changes))
+			for _, c := range changes {
+				deltas[c.UserID] = c.Delta()
+				if !c.After.UpdatedAt.Equal(now) {
+					t.Errorf("%s: UpdatedAt = %v, want %v", c.UserID, c.After.UpdatedAt, now)
+				}
+			}
+			for _, id := range tt.up {
+				if deltas[id] <= 0 {
+					t.Errorf("%s: Delta = %v, want > 0", id, deltas[id])
+				}
+			}
+			for _, id := range tt.down {
+				if deltas[id] >= 0 {
+					t.Errorf("%s: Delta = %v, want < 0", id, deltas[id])
+				}
+			}
+			for _, id := range tt.same {
+				if math.Abs(deltas[id]) > 1e-9 {
+					t.Errorf("%s: Delta = %v, want 0", id, deltas[id])
+				}
+			}
+		})
+	}
+}
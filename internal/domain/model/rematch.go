@@ -0,0 +1,98 @@
+package model
+
+import "slices"
+
+// VoteRematch は終了したゲームで再戦に投票する
+func (g *Game) VoteRematch(userID string) error {
+	if g.Status != GameStatusFinished {
+		return ErrGameIsNotFinished
+	}
+	if _, ok := g.Users[userID]; !ok {
+		return ErrInvalidTarget
+	}
+
+	if !slices.Contains(g.RematchVotes, userID) {
+		g.RematchVotes = append(g.RematchVotes, userID)
+	}
+	return nil
+}
+
+// RequiredRematchVotes は再戦に必要な投票の数 (接続している CPU 以外のユーザーの数) を返す
+func (g *Game) RequiredRematchVotes() int {
+	n := 0
+	for _, u := range g.Users {
+		if !u.IsCPU && u.IsConnected() {
+			n++
+		}
+	}
+	return n
+}
+
+// CanRematch はオーナーが投票したか、接続している全員が投票したかを返す
+func (g *Game) CanRematch() bool {
+	if g.Status != GameStatusFinished || len(g.RematchVotes) == 0 {
+		return false
+	}
+	if slices.Contains(g.RematchVotes, g.BaseRoom.OwnerID) {
+		return true
+	}
+
+	votes := 0
+	for _, id := range g.RematchVotes {
+		if u, ok := g.Users[id]; ok && u.IsConnected() {
+			votes++
+		}
+	}
+	return votes >= g.RequiredRematchVotes()
+}
+
+// Rematch は同じメンバーでロビーに戻す
+// CPU と切断しているユーザーは取り除き、取り除いたユーザーを返す
+// オーナーを取り除いた場合は、最も長く接続しているユーザーにオーナーを譲る
+func (g *Game) Rematch() []*User {
+	removed := make([]*User, 0)
+	for id, u := range g.Users {
+		if u.IsCPU || !u.IsConnected() {
+			removed = append(removed, u)
+			delete(g.Users, id)
+			continue
+		}
+		u.Reset()
+	}
+	if _, ok := g.Users[g.BaseRoom.OwnerID]; !ok {
+		if next := g.NextOwner(); next != nil {
+			g.BaseRoom.OwnerID = next.ID
+		}
+	}
+
+	g.Status = GameStatusPending
+	g.StartAt = 0
+	g.CountdownID = ""
+	g.RematchVotes = nil
+	return removed
+}
+
+// Reset はゲームの進行に関する状態を初期化する
+// 接続やチーム、攻撃対象の選び方は引き継ぐ
+func (u *User) Reset() {
+	u.Life = InitUserLife
+	u.Sequences = nil
+	u.Pos = 0
+	u.MaxPos = 0
+	u.Streak = 0
+	u.DeadAt = 0
+	u.Difficult = 0
+	u.DamageDealt = 0
+	u.DamageReceived = 0
+	u.TypedChars = 0
+	u.Mistypes = 0
+	u.LevelCounts = nil
+	u.Completed = 0
+	u.Score = 0
+	u.Ready = false
+	u.LastAttackedBy = ""
+	u.Shield = false
+	u.Reflect = false
+	u.DoubleDamage = false
+	u.FrozenUntil = 0
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Replay は1試合分のリプレイの情報
+type Replay struct {
+	ID       string // MatchID
+	RoomID   string
+	RoomName string
+	UserIDs  []string // CPU 以外の参加者
+	StartAt  int64    // unix milli
+	FinishAt int64    // unix milli
+}
+
+// ReplayEvent はリプレイに記録する1件のメッセージ
+// From が空ならサーバーからの配信、そうでなければクライアントからのメッセージ
+type ReplayEvent struct {
+	At           int64 // unix milli
+	From         string
+	Payload      json.RawMessage
+	IncludeUsers []string
+	ExcludeUsers []string
+}
+
+// NewReplay は終了したゲームからリプレイの情報を作る
+func NewReplay(g *Game, finishAt int64) *Replay {
+	userIDs := make([]string, 0, len(g.Users))
+	for _, u := range g.Users {
+		if !u.IsCPU {
+			userIDs = append(userIDs, u.ID)
+		}
+	}
+
+	return &Replay{
+		ID:       g.MatchID,
+		RoomID:   g.ID,
+		RoomName: g.BaseRoom.Name,
+		UserIDs:  userIDs,
+		StartAt:  int64(g.StartAt) * 1000,
+		FinishAt: finishAt,
+	}
+}
+
+// HasParticipant は userID が試合の参加者か
+func (r *Replay) HasParticipant(userID string) bool {
+	return slices.Contains(r.UserIDs, userID)
+}
+
+// VisibleTo は userID に配信されたメッセージか
+// 特定のユーザーだけに送ったメッセージは他の参加者には見せない
+func (e *ReplayEvent) VisibleTo(userID string) bool {
+	return len(e.IncludeUsers) == 0 || slices.Contains(e.IncludeUsers, userID)
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type RoomSort string
+
+const (
+	RoomSortCreated RoomSort = "created" // 新しい順
+	RoomSortPlayers RoomSort = "players" // 参加人数の多い順
+)
+
+var ErrInvalidRoomQuery = errors.New("invalid room query")
+
+func NewRoomSort(sort string) (RoomSort, error) {
+	switch s := RoomSort(sort); s {
+	case "":
+		return RoomSortCreated, nil
+	case RoomSortCreated, RoomSortPlayers:
+		return s, nil
+	}
+	return "", ErrInvalidRoomQuery
+}
+
+// RoomFilter はルーム一覧の絞り込み条件。非公開のルームは常に除く
+type RoomFilter struct {
+	Statuses []string // 空の場合は終了していないルーム
+	UseCPU   *bool
+	Name     string // 部分一致
+	HasSpace bool   // 空きのあるルームだけにする (参加人数で判定するので DB では絞り込まない)
+}
+
+// RoomListing は一覧に表示するルームと、Redis 上の現在の参加人数
+type RoomListing struct {
+	Room    *Room
+	UserNum int
+}
+
+func (l *RoomListing) HasSpace() bool {
+	return l.UserNum < l.Room.MaxUserNum
+}
+
+// RoomCursor は一覧の続きを取得するためのカーソル。最後に返したルームを指す
+type RoomCursor struct {
+	Sort      RoomSort  `json:"s"`
+	UserNum   int       `json:"n,omitempty"`
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+func NewRoomCursor(sort RoomSort, l *RoomListing) *RoomCursor {
+	return &RoomCursor{
+		Sort:      sort,
+		UserNum:   l.UserNum,
+		CreatedAt: l.Room.CreatedAt,
+		ID:        l.Room.ID,
+	}
+}
+
+func (c *RoomCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeRoomCursor は空文字のとき nil を返す
+func DecodeRoomCursor(s string, sort RoomSort) (*RoomCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidRoomQuery
+	}
+	var c RoomCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidRoomQuery
+	}
+	return &c, nil
+}
+
+// CompareRoomListings は sort の順に並べたとき a が先なら負の値を返す
+// 同じ場合は新しい順、さらに ID の降順にして順序を一意にする
+func CompareRoomListings(sort RoomSort, a, b *RoomListing) int {
+	if sort == RoomSortPlayers {
+		if c := cmp.Compare(b.UserNum, a.UserNum); c != 0 {
+			return c
+		}
+	}
+	if c := b.Room.CreatedAt.Compare(a.Room.CreatedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.Room.ID, a.Room.ID)
+}
+
+// IsAfter は l がカーソルより後ろにあるか
+func (c *RoomCursor) IsAfter(l *RoomListing) bool {
+	cur := &RoomListing{
+		Room:    &Room{ID: c.ID, CreatedAt: c.CreatedAt},
+		UserNum: c.UserNum,
+	}
+	return CompareRoomListings(c.Sort, cur, l) < 0
+}
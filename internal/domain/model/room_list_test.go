@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoomCursorEncodeDecode(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := &RoomListing{
+		Room:    &Room{ID: "room", CreatedAt: createdAt},
+		UserNum: 3,
+	}
+
+	tests := []struct {
+		name    string
+		s       string
+		sort    RoomSort
+		want    *RoomCursor
+		wantErr error
+	}{
+		{
+			name: "empty",
+			s:    "",
+			sort: RoomSortCreated,
+		},
+		{
+			name: "round trip",
+			s:    NewRoomCursor(RoomSortPlayers, l).Encode(),
+			sort: RoomSortPlayers,
+			want: &RoomCursor{Sort: RoomSortPlayers, UserNum: 3, CreatedAt: createdAt, ID: "room"},
+		},
+		{
+			name:    "different sort",
+			s:       NewRoomCursor(RoomSortPlayers, l).Encode(),
+			sort:    RoomSortCreated,
+			wantErr: ErrInvalidRoomQuery,
+		},
+		{
+			name:    "not base64",
+			s:       "!!!",
+			sort:    RoomSortCreated,
+			wantErr: ErrInvalidRoomQuery,
+		},
+		{
+			name:    "not json",
+			s:       "bm90IGpzb24",
+			sort:    RoomSortCreated,
+			wantErr: ErrInvalidRoomQuery,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeRoomCursor(tt.s, tt.sort)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("DecodeRoomCursor() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Sort != tt.want.Sort || got.UserNum != tt.want.UserNum || !got.CreatedAt.Equal(tt.want.CreatedAt) || got.ID != tt.want.ID {
+				t.Errorf("DecodeRoomCursor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoomCursorIsAfter(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	listing := func(id string, createdAt time.Time, userNum int) *RoomListing {
+		return &RoomListing{Room: &Room{ID: id, CreatedAt: createdAt}, UserNum: userNum}
+	}
+	cur := listing("m", base, 2)
+
+	tests := []struct {
+		name string
+		sort RoomSort
+		l    *RoomListing
+		want bool
+	}{
+		{"older is after", RoomSortCreated, listing("z", base.Add(-time.Second), 2), true},
+		{"newer is before", RoomSortCreated, listing("a", base.Add(time.Second), 2), false},
+		{"same time smaller id is after", RoomSortCreated, listing("a", base, 2), true},
+		{"same time larger id is before", RoomSortCreated, listing("z", base, 2), false},
+		{"itself is not after", RoomSortCreated, listing("m", base, 2), false},
+		{"fewer players is after", RoomSortPlayers, listing("z", base.Add(time.Second), 1), true},
+		{"more players is before", RoomSortPlayers, listing("a", base.Add(-time.Second), 3), false},
+		{"same players older is after", RoomSortPlayers, listing("z", base.Add(-time.Second), 2), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewRoomCursor(tt.sort, cur)
+			if got := c.IsAfter(tt.l); got != tt.want {
+				t.Errorf("IsAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// UserStats はユーザーのタイピングの成績
+// 1試合分の成績も、それを足し合わせた通算の成績も表す
+type UserStats struct {
+	UserID       string
+	GamesPlayed  int
+	Wins         int
+	TypedChars   int
+	Mistypes     int
+	TypingMillis int64
+	BestWPM      float64
+	LevelCounts  map[int]int // 完了したシーケンスのレベルごとの数
+}
+
+// WPM は1分あたりの入力単語数 (1 word = 5 文字) を返す
+func WPM(chars int, millis int64) float64 {
+	if millis <= 0 {
+		return 0
+	}
+	return float64(chars) / 5 / (float64(millis) / float64(time.Minute.Milliseconds()))
+}
+
+// AverageWPM は通算の WPM を返す
+func (s *UserStats) AverageWPM() float64 {
+	return WPM(s.TypedChars, s.TypingMillis)
+}
+
+// Accuracy は正しく入力できた文字の割合を返す
+func (s *UserStats) Accuracy() float64 {
+	total := s.TypedChars + s.Mistypes
+	if total == 0 {
+		return 0
+	}
+	return float64(s.TypedChars) / float64(total)
+}
+
+// FavoriteLevels は完了した数の多いレベルを n 個まで返す
+func (s *UserStats) FavoriteLevels(n int) []int {
+	levels := make([]int, 0, len(s.LevelCounts))
+	for level := range s.LevelCounts {
+		levels = append(levels, level)
+	}
+	slices.SortFunc(levels, func(a, b int) int {
+		if s.LevelCounts[a] != s.LevelCounts[b] {
+			return s.LevelCounts[b] - s.LevelCounts[a]
+		}
+		return a - b
+	})
+	return levels[:min(n, len(levels))]
+}
+
+// Add は1試合分の成績を通算の成績に足す
+func (s *UserStats) Add(game *UserStats) {
+	s.GamesPlayed += game.GamesPlayed
+	s.Wins += game.Wins
+	s.TypedChars += game.TypedChars
+	s.Mistypes += game.Mistypes
+	s.TypingMillis += game.TypingMillis
+	s.BestWPM = max(s.BestWPM, game.BestWPM)
+	if s.LevelCounts == nil {
+		s.LevelCounts = map[int]int{}
+	}
+	for level, n := range game.LevelCounts {
+		s.LevelCounts[level] += n
+	}
+}
+
+// NewGameStats は終了したゲームから CPU 以外のユーザーの1試合分の成績を作る
+// 入力していた時間は開始の合図から死亡 (生存していればゲームの終了) までとする
+func NewGameStats(g *Game, results []*GameResult, finishedAt time.Time) []*UserStats {
+	stats := make([]*UserStats, 0, len(results))
+	for _, r := range results {
+		u, ok := g.Users[r.UserID]
+		if !ok || u.IsCPU {
+			continue
+		}
+
+		end := finishedAt
+		if u.DeadAt > 0 {
+			end = time.Unix(int64(u.DeadAt), 0)
+		}
+		millis := max(0, end.Sub(time.Unix(int64(g.StartAt), 0)).Milliseconds())
+
+		// チーム戦では勝ったチーム全員を勝ちとする
+		wins := 0
+		if r.Standing() == 1 {
+			wins = 1
+		}
+
+		stats = append(stats, &UserStats{
+			UserID:       u.ID,
+			GamesPlayed:  1,
+			Wins:         wins,
+			TypedChars:   u.TypedChars,
+			Mistypes:     u.Mistypes,
+			TypingMillis: millis,
+			BestWPM:      WPM(u.TypedChars, millis),
+			LevelCounts:  u.LevelCounts,
+		})
+	}
+	return stats
+}
+
+// CountLevel は完了したシーケンスのレベルを数える
+func (u *User) CountLevel(level int) {
+	if u.LevelCounts == nil {
+		u.LevelCounts = map[int]int{}
+	}
+	u.LevelCounts[level]++
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// IsTeamMode はチーム戦かどうかを返す
+func (g *Game) IsTeamMode() bool {
+	return g.BaseRoom != nil && g.BaseRoom.TeamNum > 0
+}
+
+// IsTeammate は a と b が同じチームかどうかを返す
+func (g *Game) IsTeammate(a, b *User) bool {
+	return g.IsTeamMode() && a.Team != 0 && a.Team == b.Team
+}
+
+// AssignTeam はユーザーをチームに割り当てる
+func (g *Game) AssignTeam(userID string, team int) error {
+	if !g.IsTeamMode() {
+		return ErrNotTeamMode
+	}
+	if g.Status != GameStatusPending {
+		return ErrGameIsStarted
+	}
+	if team < 1 || team > g.BaseRoom.TeamNum {
+		return ErrInvalidTeam
+	}
+	user, ok := g.Users[userID]
+	if !ok {
+		return ErrInvalidTarget
+	}
+
+	user.Team = team
+	return nil
+}
+
+// FillTeams はチームが未所属のユーザーを人数の少ないチームに割り当てる
+// 割り当てを変更したユーザーを返す
+func (g *Game) FillTeams() []*User {
+	if !g.IsTeamMode() {
+		return nil
+	}
+
+	counts := make([]int, g.BaseRoom.TeamNum+1)
+	users := g.sortedUsers()
+	for _, u := range users {
+		if u.Team > 0 && u.Team <= g.BaseRoom.TeamNum {
+			counts[u.Team]++
+		}
+	}
+
+	changed := make([]*User, 0)
+	for _, u := range users {
+		if u.Team > 0 && u.Team <= g.BaseRoom.TeamNum {
+			continue
+		}
+		team := 1
+		for t := 2; t <= g.BaseRoom.TeamNum; t++ {
+			if counts[t] < counts[team] {
+				team = t
+			}
+		}
+		u.Team = team
+		counts[team]++
+		changed = append(changed, u)
+	}
+
+	return changed
+}
+
+// AliveTeams は生存者のいるチームを返す
+func (g *Game) AliveTeams() []int {
+	teams := make([]int, 0)
+	for _, u := range g.Users {
+		if u.Life > 0 && !slices.Contains(teams, u.Team) {
+			teams = append(teams, u.Team)
+		}
+	}
+	slices.Sort(teams)
+	return teams
+}
+
+// SelectHealTarget は回復の対象を選ぶ
+// チーム戦では、生きている味方 (自分を含む) のうち最も難易度が高いユーザーを選ぶ
+func (g *Game) SelectHealTarget(healer *User) *User {
+	target := healer
+	if !g.IsTeamMode() {
+		return target
+	}
+
+	for _, u := range g.sortedUsers() {
+		if u.Life > 0 && g.IsTeammate(u, healer) && u.Difficult > target.Difficult {
+			target = u
+		}
+	}
+	return target
+}
+
+// teamRanks はチームの順位を計算する
+// users は個人の順位順に並んでいる必要があり、最上位のメンバーの順位が高いチームほど上位とする
+func (g *Game) teamRanks(users []*User) map[int]int {
+	ranks := make(map[int]int)
+	if !g.IsTeamMode() {
+		return ranks
+	}
+
+	for _, u := range users {
+		if _, ok := ranks[u.Team]; !ok {
+			ranks[u.Team] = len(ranks) + 1
+		}
+	}
+	return ranks
+}
+
+func (g *Game) sortedUsers() []*User {
+	users := make([]*User, 0, len(g.Users))
+	for _, u := range g.Users {
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b *User) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return users
+}
@@ -1,9 +1,13 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 type RoomStatus string
 type GameStatus string
+type TargetMode string
 
 const (
 	GameStatusPending  GameStatus = "pending"
@@ -18,6 +22,37 @@ const (
 	RoomStatusFinish  = "finish"
 )
 
+const (
+	TargetModeRandom  TargetMode = "random"
+	TargetModeLeader  TargetMode = "leader"
+	TargetModeRevenge TargetMode = "revenge"
+	TargetModeWeakest TargetMode = "weakest"
+	TargetModeUser    TargetMode = "user"
+)
+
+const (
+	SequenceTypeDefault      = "default"
+	SequenceTypeHeal         = "heal"
+	SequenceTypeShield       = "shield"
+	SequenceTypeReflect      = "reflect"
+	SequenceTypeFreeze       = "freeze"
+	SequenceTypeDoubleDamage = "double"
+)
+
+const (
+	WinConditionLastStanding = "last_standing"
+	WinConditionFirstToN     = "first_to_n"
+	WinConditionHighestScore = "highest_score"
+	WinConditionTopK         = "top_k"
+)
+
+const (
+	ConnectionConnected    = "connected"
+	ConnectionDisconnected = "disconnected"
+)
+
+const FreezeDuration = 3 * time.Second
+
 const GameStartDelay = 5 // sec
 const InitUserLife = 5
 
@@ -38,7 +73,45 @@ func (s GameStatus) String() string {
 	return string(s)
 }
 
+func NewTargetMode(mode string) (TargetMode, error) {
+	switch m := TargetMode(mode); m {
+	case TargetModeRandom, TargetModeLeader, TargetModeRevenge, TargetModeWeakest, TargetModeUser:
+		return m, nil
+	case "":
+		return TargetModeRandom, nil
+	default:
+		return "", ErrInvalidTargetMode
+	}
+}
+
+func (m TargetMode) String() string {
+	return string(m)
+}
+
 var (
-	ErrMaxUserNum    error = errors.New("max user num")
-	ErrGameIsStarted error = errors.New("game is started")
+	ErrMaxUserNum        error = errors.New("max user num")
+	ErrGameNotFound      error = errors.New("game not found")
+	ErrGameIsStarted     error = errors.New("game is started")
+	ErrGameIsNotPlaying  error = errors.New("game is not playing")
+	ErrGameIsNotFinished error = errors.New("game is not finished")
+	ErrGameIsNotStarted  error = errors.New("game has not started yet")
+	ErrAlreadyJoined     error = errors.New("already joined")
+	ErrReconnectExpired  error = errors.New("reconnect grace period expired")
+	ErrStaleConnection   error = errors.New("stale connection")
+	ErrNoSequence        error = errors.New("no sequence")
+	ErrMistype           error = errors.New("mistype")
+	ErrFrozen            error = errors.New("frozen")
+
+	ErrInvalidTargetMode error = errors.New("invalid target mode")
+	ErrInvalidTarget     error = errors.New("invalid target")
+	ErrInvalidTeam       error = errors.New("invalid team")
+	ErrNotTeamMode       error = errors.New("room is not team mode")
+	ErrNotEnoughTeams    error = errors.New("not enough teams")
+	ErrNotOwner          error = errors.New("you are not owner")
+	ErrNotEnoughReady    error = errors.New("not enough players are ready")
+	ErrBanned            error = errors.New("you are banned from this room")
+	ErrNotInQueue        error = errors.New("not in matchmaking queue")
+	ErrWrongPassword     error = errors.New("wrong password")
+
+	ErrInvalidWinCondition error = errors.New("invalid win condition")
 )
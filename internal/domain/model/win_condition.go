@@ -0,0 +1,145 @@
+package model
+
+import "cmp"
+
+// WinCondition はゲームの終了条件と順位の付け方を表す
+type WinCondition interface {
+	// IsOver はゲームが終了したかを返す
+	IsOver(g *Game) bool
+	// Compare は a が b より上位なら負の値を返す
+	Compare(a, b *User) int
+}
+
+// NewWinCondition はルームの設定から終了条件を返す
+func NewWinCondition(room *Room) WinCondition {
+	switch room.WinCondition {
+	case WinConditionFirstToN:
+		return &firstToN{n: room.WinTarget}
+	case WinConditionHighestScore:
+		return &highestScore{}
+	case WinConditionTopK:
+		return &topK{k: room.WinTarget}
+	default:
+		return &lastStanding{}
+	}
+}
+
+// WinCondition はゲームの終了条件を返す
+func (g *Game) WinCondition() WinCondition {
+	if g.BaseRoom == nil {
+		return &lastStanding{}
+	}
+	return NewWinCondition(g.BaseRoom)
+}
+
+// ValidateWinCondition はルームの終了条件の設定が正しいかを返す
+func ValidateWinCondition(room *Room) error {
+	switch room.WinCondition {
+	case "", WinConditionLastStanding:
+		return nil
+	case WinConditionFirstToN:
+		// チーム戦では1人の完了数で決めると順位がチームの順位と合わない
+		if room.WinTarget <= 0 || room.TeamNum > 0 {
+			return ErrInvalidWinCondition
+		}
+	case WinConditionHighestScore:
+		// 制限時間が無いと終わらない
+		if room.Duration <= 0 {
+			return ErrInvalidWinCondition
+		}
+	case WinConditionTopK:
+		// 開始できる最少人数でも K 人より多くないと、始まった時点で終わってしまう
+		if room.WinTarget <= 0 || room.WinTarget >= room.MinUserNum {
+			return ErrInvalidWinCondition
+		}
+		// チーム戦では生き残ったチームの数で数える
+		if room.TeamNum > 0 && room.WinTarget >= room.TeamNum {
+			return ErrInvalidWinCondition
+		}
+	default:
+		return ErrInvalidWinCondition
+	}
+	return nil
+}
+
+// compareBySurvival は生存者をライフ、与えたダメージの順に上位とし、死亡者は後に死んだほど上位とする
+func compareBySurvival(a, b *User) int {
+	aliveA, aliveB := a.DeadAt == 0, b.DeadAt == 0
+	if aliveA != aliveB {
+		if aliveA {
+			return -1
+		}
+		return 1
+	}
+	if aliveA {
+		return cmp.Or(
+			cmp.Compare(b.Life, a.Life),
+			cmp.Compare(b.DamageDealt, a.DamageDealt),
+			cmp.Compare(a.ID, b.ID),
+		)
+	}
+	return cmp.Or(
+		cmp.Compare(b.DeadAt, a.DeadAt),
+		cmp.Compare(a.ID, b.ID),
+	)
+}
+
+// lastStanding は最後の1人 (チーム戦では1チーム) になるまで続ける
+type lastStanding struct{}
+
+func (c *lastStanding) IsOver(g *Game) bool {
+	if g.IsTeamMode() {
+		return len(g.AliveTeams()) <= 1
+	}
+	return g.AliveUserNum() <= 1
+}
+
+func (c *lastStanding) Compare(a, b *User) int {
+	return compareBySurvival(a, b)
+}
+
+// firstToN は誰かが N 個のシーケンスを完了したら終了する
+type firstToN struct {
+	n int
+}
+
+func (c *firstToN) IsOver(g *Game) bool {
+	for _, u := range g.Users {
+		if u.Completed >= c.n {
+			return true
+		}
+	}
+	// 全員が死亡しても終了する
+	return g.AliveUserNum() == 0
+}
+
+func (c *firstToN) Compare(a, b *User) int {
+	return cmp.Or(cmp.Compare(b.Completed, a.Completed), compareBySurvival(a, b))
+}
+
+// highestScore は制限時間まで続け、スコアの高い順に順位を付ける
+type highestScore struct{}
+
+func (c *highestScore) IsOver(g *Game) bool {
+	return g.AliveUserNum() == 0
+}
+
+func (c *highestScore) Compare(a, b *User) int {
+	return cmp.Or(cmp.Compare(b.Score, a.Score), compareBySurvival(a, b))
+}
+
+// topK は生存者が K 人 (チーム戦では K チーム) 以下になったら終了する
+type topK struct {
+	k int
+}
+
+func (c *topK) IsOver(g *Game) bool {
+	if g.IsTeamMode() {
+		return len(g.AliveTeams()) <= c.k
+	}
+	return g.AliveUserNum() <= c.k
+}
+
+func (c *topK) Compare(a, b *User) int {
+	return compareBySurvival(a, b)
+}
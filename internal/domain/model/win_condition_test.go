@@ -0,0 +1,197 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestGame(room *Room, users ...*User) *Game {
+	g := &Game{
+		ID:       "room",
+		Users:    make(map[string]*User, len(users)),
+		Status:   GameStatusPlaying,
+		BaseRoom: room,
+	}
+	for _, u := range users {
+		g.Users[u.ID] = u
+	}
+	return g
+}
+
+func TestValidateWinCondition(t *testing.T) {
+	tests := []struct {
+		name string
+		room *Room
+		want error
+	}{
+		{"empty", &Room{}, nil},
+		{"last standing", &Room{WinCondition: WinConditionLastStanding}, nil},
+		{"first to n", &Room{WinCondition: WinConditionFirstToN, WinTarget: 10}, nil},
+		{"first to n without target", &Room{WinCondition: WinConditionFirstToN}, ErrInvalidWinCondition},
+		{"first to n with teams", &Room{WinCondition: WinConditionFirstToN, WinTarget: 10, TeamNum: 2}, ErrInvalidWinCondition},
+		{"highest score", &Room{WinCondition: WinConditionHighestScore, Duration: 60}, nil},
+		{"highest score without duration", &Room{WinCondition: WinConditionHighestScore}, ErrInvalidWinCondition},
+		{"top k", &Room{WinCondition: WinConditionTopK, WinTarget: 2, MinUserNum: 3}, nil},
+		{"top k not less than min users", &Room{WinCondition: WinConditionTopK, WinTarget: 2, MinUserNum: 2}, ErrInvalidWinCondition},
+		{"top k with teams", &Room{WinCondition: WinConditionTopK, WinTarget: 1, MinUserNum: 4, TeamNum: 2}, nil},
+		{"top k not less than teams", &Room{WinCondition: WinConditionTopK, WinTarget: 2, MinUserNum: 4, TeamNum: 2}, ErrInvalidWinCondition},
+		{"unknown", &Room{WinCondition: "unknown"}, ErrInvalidWinCondition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateWinCondition(tt.room); !errors.Is(err, tt.want) {
+				t.Errorf("ValidateWinCondition() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWinConditionIsOver(t *testing.T) {
+	tests := []struct {
+		name  string
+		room  *Room
+		users []*User
+		want  bool
+	}{
+		{
+			name:  "last standing with two alive",
+			room:  &Room{WinCondition: WinConditionLastStanding},
+			users: []*User{{ID: "a", Life: 1}, {ID: "b", Life: 1}, {ID: "c"}},
+			want:  false,
+		},
+		{
+			name:  "last standing with one alive",
+			room:  &Room{WinCondition: WinConditionLastStanding},
+			users: []*User{{ID: "a", Life: 1}, {ID: "b"}, {ID: "c"}},
+			want:  true,
+		},
+		{
+			name:  "last standing with one team alive",
+			room:  &Room{WinCondition: WinConditionLastStanding, TeamNum: 2},
+			users: []*User{{ID: "a", Life: 1, Team: 1}, {ID: "b", Life: 1, Team: 1}, {ID: "c", Team: 2}},
+			want:  true,
+		},
+		{
+			name:  "first to n reached",
+			room:  &Room{WinCondition: WinConditionFirstToN, WinTarget: 3},
+			users: []*User{{ID: "a", Life: 1, Completed: 3}, {ID: "b", Life: 1}},
+			want:  true,
+		},
+		{
+			name:  "first to n not reached",
+			room:  &Room{WinCondition: WinConditionFirstToN, WinTarget: 3},
+			users: []*User{{ID: "a", Life: 1, Completed: 2}, {ID: "b", Life: 1}},
+			want:  false,
+		},
+		{
+			name:  "first to n all dead",
+			room:  &Room{WinCondition: WinConditionFirstToN, WinTarget: 3},
+			users: []*User{{ID: "a"}, {ID: "b"}},
+			want:  true,
+		},
+		{
+			name:  "highest score with one alive",
+			room:  &Room{WinCondition: WinConditionHighestScore, Duration: 60},
+			users: []*User{{ID: "a", Life: 1}, {ID: "b"}},
+			want:  false,
+		},
+		{
+			name:  "top k with more alive",
+			room:  &Room{WinCondition: WinConditionTopK, WinTarget: 2},
+			users: []*User{{ID: "a", Life: 1}, {ID: "b", Life: 1}, {ID: "c", Life: 1}},
+			want:  false,
+		},
+		{
+			name:  "top k reached",
+			room:  &Room{WinCondition: WinConditionTopK, WinTarget: 2},
+			users: []*User{{ID: "a", Life: 1}, {ID: "b", Life: 1}, {ID: "c"}},
+			want:  true,
+		},
+		{
+			name:  "top k counts teams",
+			room:  &Room{WinCondition: WinConditionTopK, WinTarget: 1, TeamNum: 3},
+			users: []*User{{ID: "a", Life: 1, Team: 1}, {ID: "b", Life: 1, Team: 1}, {ID: "c", Life: 1, Team: 2}, {ID: "d", Team: 3}},
+			want:  false,
+		},
+		{
+			name:  "top k with one team left",
+			room:  &Room{WinCondition: WinConditionTopK, WinTarget: 1, TeamNum: 3},
+			users: []*User{{ID: "a", Life: 1, Team: 1}, {ID: "b", Life: 1, Team: 1}, {ID: "c", Team: 2}, {ID: "d", Team: 3}},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGame(tt.room, tt.users...)
+			if got := g.WinCondition().IsOver(g); got != tt.want {
+				t.Errorf("IsOver() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWinConditionCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		room *Room
+		a, b *User
+		want int
+	}{
+		{
+			name: "alive before dead",
+			room: &Room{WinCondition: WinConditionLastStanding},
+			a:    &User{ID: "a", Life: 1},
+			b:    &User{ID: "b", DeadAt: 100},
+			want: -1,
+		},
+		{
+			name: "more life first",
+			room: &Room{WinCondition: WinConditionLastStanding},
+			a:    &User{ID: "a", Life: 1},
+			b:    &User{ID: "b", Life: 2},
+			want: 1,
+		},
+		{
+			name: "more damage dealt first",
+			room: &Room{WinCondition: WinConditionLastStanding},
+			a:    &User{ID: "a", Life: 1, DamageDealt: 5},
+			b:    &User{ID: "b", Life: 1, DamageDealt: 3},
+			want: -1,
+		},
+		{
+			name: "later death first",
+			room: &Room{WinCondition: WinConditionLastStanding},
+			a:    &User{ID: "a", DeadAt: 100},
+			b:    &User{ID: "b", DeadAt: 200},
+			want: 1,
+		},
+		{
+			name: "first to n by completed",
+			room: &Room{WinCondition: WinConditionFirstToN, WinTarget: 3},
+			a:    &User{ID: "a", DeadAt: 100, Completed: 3},
+			b:    &User{ID: "b", Life: 1, Completed: 1},
+			want: -1,
+		},
+		{
+			name: "highest score by score",
+			room: &Room{WinCondition: WinConditionHighestScore, Duration: 60},
+			a:    &User{ID: "a", Life: 1, Score: 10},
+			b:    &User{ID: "b", Life: 1, Score: 20},
+			want: 1,
+		},
+		{
+			name: "tie broken by id",
+			room: &Room{WinCondition: WinConditionTopK, WinTarget: 1},
+			a:    &User{ID: "a", Life: 1},
+			b:    &User{ID: "b", Life: 1},
+			want: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewWinCondition(tt.room).Compare(tt.a, tt.b); got != tt.want {
+				t.Errorf("Compare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
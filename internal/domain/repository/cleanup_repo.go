@@ -0,0 +1,11 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+type CleanupRepository interface {
+	SaveCleanups(ctx context.Context, cleanups []*model.Cleanup) error
+}
@@ -2,17 +2,36 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Simo-C3/stego2-server/internal/domain/model"
 )
 
 type GameRepository interface {
+	// GetGameByID はゲームがない場合 model.ErrGameNotFound を返す
 	GetGameByID(ctx context.Context, id string) (*model.Game, error)
 	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	// GetUserNums はルームごとの現在の参加人数を返す。ゲームがないルームは 0 人になる
+	GetUserNums(ctx context.Context, ids []string) (map[string]int, error)
+	// CreateGame はゲームがまだない場合だけ保存する。既にある場合は false を返す
+	CreateGame(ctx context.Context, game *model.Game) (bool, error)
 	UpdateGame(ctx context.Context, game *model.Game) error
 	UpdateUser(ctx context.Context, user *model.User) error
 	DeleteGame(ctx context.Context, id string) error
 	DeleteUser(ctx context.Context, id string) error
+	// DeleteStaleUser は updatedBefore より後に書き込まれていなければユーザーを消す。消さなかった場合は false を返す
+	DeleteStaleUser(ctx context.Context, id string, updatedBefore time.Time) (bool, error)
+	// EditGame はゲームがない場合 model.ErrGameNotFound を返す
 	EditGame(ctx context.Context, gameID string, fn func(*model.Game) error) error
 	EditUser(ctx context.Context, userID string, fn func(*model.User) error) error
+	// GetGames は ids のうち存在するゲームを返す
+	GetGames(ctx context.Context, ids []string) (map[string]*model.Game, error)
+	// GetGameIDs は期限切れになっていないゲームの ID を返す
+	GetGameIDs(ctx context.Context) ([]string, error)
+	// GetUserIDs は updatedBefore より前に最後に書き込まれたユーザーの ID を返す
+	GetUserIDs(ctx context.Context, updatedBefore time.Time) ([]string, error)
+	// PruneIndex は期限切れになったゲームとユーザーを一覧から外す
+	PruneIndex(ctx context.Context, now time.Time) error
+	// Lock は複数のサーバーで同時に同じ処理をしないように ttl の間ロックを取る
+	Lock(ctx context.Context, name string, ttl time.Duration) (bool, error)
 }
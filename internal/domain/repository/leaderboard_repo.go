@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+// LeaderboardRepository は全ての集計期間のランキングをまとめて更新する
+type LeaderboardRepository interface {
+	// IncrScore はスコアに by を足す
+	IncrScore(ctx context.Context, kind model.LeaderboardKind, userID, name string, by float64, now time.Time) error
+	// MaxScore はスコアが今より高い場合だけ更新する
+	MaxScore(ctx context.Context, kind model.LeaderboardKind, userID, name string, score float64, now time.Time) error
+	// SetScore はスコアを上書きする
+	SetScore(ctx context.Context, kind model.LeaderboardKind, userID, name string, score float64, now time.Time) error
+	GetEntries(ctx context.Context, kind model.LeaderboardKind, period model.LeaderboardPeriod, now time.Time, offset, limit int) ([]*model.LeaderboardEntry, int, error)
+	// GetEntry はユーザーの順位を返す。ランキングにいない場合は nil を返す
+	GetEntry(ctx context.Context, kind model.LeaderboardKind, period model.LeaderboardPeriod, now time.Time, userID string) (*model.LeaderboardEntry, error)
+}
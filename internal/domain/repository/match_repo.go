@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+type MatchRepository interface {
+	SaveMatch(ctx context.Context, match *model.MatchRecord) error
+	// GetUserMatches はユーザーが参加した試合を新しい順に返す (Results はそのユーザーの成績のみ)
+	GetUserMatches(ctx context.Context, userID string, limit, offset int) ([]*model.MatchRecord, error)
+	GetMatch(ctx context.Context, id string) (*model.MatchRecord, error)
+}
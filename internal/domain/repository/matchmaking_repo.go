@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+type MatchmakingRepository interface {
+	// Enqueue は待ち行列にユーザーを追加する。既に並んでいる場合は上書きする
+	Enqueue(ctx context.Context, ticket *model.MatchTicket) error
+	// Dequeue は待ち行列からユーザーを外す。並んでいなかった場合は false を返す
+	Dequeue(ctx context.Context, userID string) (bool, error)
+	GetTicket(ctx context.Context, userID string) (*model.MatchTicket, error)
+	GetTickets(ctx context.Context) ([]*model.MatchTicket, error)
+	// Touch は結果を取りに来た時刻を更新する。並んでいない場合は false を返す
+	Touch(ctx context.Context, userID string, now time.Time) (bool, error)
+	// Lock は複数のサーバーで同時にマッチングしないように ttl の間ロックを取る
+	Lock(ctx context.Context, ttl time.Duration) (bool, error)
+	// Reserve はマッチングしたユーザーが参加するまでの ttl の間、ルームの席を確保する
+	Reserve(ctx context.Context, roomID, userID string, ttl time.Duration) error
+	// Release はユーザーがルームに参加したときに、確保していた席を解放する
+	Release(ctx context.Context, roomID, userID string) error
+	// GetReserved は期限の切れていない確保済みの席数を返す
+	GetReserved(ctx context.Context, roomID string) (int, error)
+	// IsReserved はユーザーが期限の切れていない席を確保しているかを返す
+	IsReserved(ctx context.Context, roomID, userID string) (bool, error)
+	// PushResult はユーザーにマッチングの結果を渡す
+	PushResult(ctx context.Context, userID string, result *model.MatchFound, ttl time.Duration) error
+	// WaitResult は timeout まで結果を待つ。結果がない場合は nil を返す
+	WaitResult(ctx context.Context, userID string, timeout time.Duration) (*model.MatchFound, error)
+}
@@ -8,5 +8,9 @@ import (
 
 type OTPRepository interface {
 	GenerateOTP(ctx context.Context, userID, name string) (*model.OTP, error)
+	// GenerateRoomOTP は roomID のルームにだけ参加できる OTP を生成する
+	GenerateRoomOTP(ctx context.Context, roomID, userID, name string) (*model.OTP, error)
+	// GetOTPRoom は OTP が使えるルームを返す。どのルームにも使える OTP の場合は空文字を返す
+	GetOTPRoom(ctx context.Context, otp string) (string, error)
 	VerifyOTP(ctx context.Context, otp string) (string, error)
 }
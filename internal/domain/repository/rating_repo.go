@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+type RatingRepository interface {
+	// GetRatings はユーザーのレーティングを返す。まだ試合をしていないユーザーは初期値を返す
+	GetRatings(ctx context.Context, userIDs []string) (map[string]*model.Rating, error)
+	// EditRatings はユーザーのレーティングをロックして fn で更新し、変化を保存して履歴に残す
+	// 読み込みから保存までを1つのトランザクションで行う
+	EditRatings(ctx context.Context, matchID string, userIDs []string, fn func(map[string]*model.Rating) []*model.RatingChange) ([]*model.RatingChange, error)
+	GetHistory(ctx context.Context, userID string, limit int) ([]*model.RatingHistory, error)
+}
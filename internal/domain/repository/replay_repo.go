@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+type ReplayRepository interface {
+	// Record はルームで進行中の試合にメッセージを記録する
+	Record(ctx context.Context, roomID string, event *model.ReplayEvent) error
+	// Reset はルームに記録したメッセージを捨てる
+	Reset(ctx context.Context, roomID string) error
+	// Save はルームに記録したメッセージをリプレイとして ttl の間保存する
+	Save(ctx context.Context, replay *model.Replay, ttl time.Duration) error
+	GetReplay(ctx context.Context, id string) (*model.Replay, error)
+	GetEvents(ctx context.Context, id string) ([]*model.ReplayEvent, error)
+	GetUserReplays(ctx context.Context, userID string, limit int) ([]*model.Replay, error)
+}
@@ -2,14 +2,22 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Simo-C3/stego2-server/internal/domain/model"
 )
 
 type RoomRepository interface {
-	GetRooms(ctx context.Context) ([]*model.Room, error)
+	// GetRooms は filter に合う非公開でないルームを新しい順に返す。合計件数も返す
+	// after を指定するとその続きから返し、limit が 0 の場合は全件返す
+	GetRooms(ctx context.Context, filter *model.RoomFilter, after *model.RoomCursor, limit int) ([]*model.Room, int, error)
 	CreateRoom(ctx context.Context, room *model.Room) (string, error)
-	Matching(ctx context.Context) (string, error)
+	// GetPendingRooms はマッチングで参加できる待機中のルームを返す
+	GetPendingRooms(ctx context.Context) ([]*model.Room, error)
 	GetRoomByID(ctx context.Context, id string) (*model.Room, error)
+	// GetActiveRooms は createdBefore より前に作られた、終了していないルームを非公開のものも含めて返す
+	GetActiveRooms(ctx context.Context, createdBefore time.Time) ([]*model.Room, error)
+	// GetRoomByInviteCode は招待コードで終了していないルームを探す
+	GetRoomByInviteCode(ctx context.Context, code string) (*model.Room, error)
 	UpdateRoom(ctx context.Context, room *model.Room) error
 }
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type SessionRepository interface {
+	IssueReconnectToken(ctx context.Context, roomID, userID string, ttl time.Duration) (string, error)
+	VerifyReconnectToken(ctx context.Context, token string) (roomID string, userID string, err error)
+	RevokeReconnectToken(ctx context.Context, token string) error
+}
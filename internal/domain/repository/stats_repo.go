@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+type StatsRepository interface {
+	// AddGameStats は1試合分の成績を各ユーザーの通算の成績に足す
+	AddGameStats(ctx context.Context, stats []*model.UserStats) error
+	// GetUserStats はユーザーの通算の成績を返す。まだ試合をしていない場合は空の成績を返す
+	GetUserStats(ctx context.Context, userID string) (*model.UserStats, error)
+}
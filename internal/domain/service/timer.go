@@ -0,0 +1,8 @@
+package service
+
+import "context"
+
+type Timer interface {
+	Start(ctx context.Context, gameID string, duration, delay int) error
+	Stop(ctx context.Context, gameID string) error
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+const (
+	defaultGameLimit = 20
+	maxGameLimit     = 100
+)
+
+type GameHandler struct {
+	repo repository.MatchRepository
+}
+
+func NewGameHandler(matchRepo repository.MatchRepository) *GameHandler {
+	return &GameHandler{
+		repo: matchRepo,
+	}
+}
+
+func convertToSchemaGame(match *model.MatchRecord) *schema.Game {
+	results := make([]*schema.GameResult, 0, len(match.Results))
+	for _, r := range match.Results {
+		results = append(results, &schema.GameResult{
+			UserID:         r.UserID,
+			DisplayName:    r.DisplayName,
+			IsCPU:          r.IsCPU,
+			Rank:           r.Rank,
+			Team:           r.Team,
+			TeamRank:       r.TeamRank,
+			DeadAt:         r.DeadAt,
+			Completed:      r.Completed,
+			DamageDealt:    r.DamageDealt,
+			DamageReceived: r.DamageReceived,
+		})
+	}
+
+	return &schema.Game{
+		ID:           match.ID,
+		RoomID:       match.RoomID,
+		RoomName:     match.RoomName,
+		WinCondition: match.WinCondition,
+		StartedAt:    match.StartedAt.Unix(),
+		FinishedAt:   match.FinishedAt.Unix(),
+		Results:      results,
+	}
+}
+
+// GetMyGames は自分が参加した試合を新しい順に返す
+func (h *GameHandler) GetMyGames(c echo.Context) error {
+	var req schema.GetGamesQuery
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultGameLimit
+	}
+	req.Limit = min(req.Limit, maxGameLimit)
+	req.Offset = max(req.Offset, 0)
+
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	matches, err := h.repo.GetUserMatches(c.Request().Context(), userID, req.Limit, req.Offset)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	res := make([]*schema.Game, 0, len(matches))
+	for _, match := range matches {
+		res = append(res, convertToSchemaGame(match))
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetGame は試合の全員の成績を返す
+func (h *GameHandler) GetGame(c echo.Context) error {
+	match, err := h.repo.GetMatch(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusNotFound, "game not found")
+	}
+
+	return c.JSON(http.StatusOK, convertToSchemaGame(match))
+}
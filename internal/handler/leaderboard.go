@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+const (
+	defaultLeaderboardLimit = 20
+	maxLeaderboardLimit     = 100
+)
+
+type LeaderboardHandler struct {
+	repo repository.LeaderboardRepository
+}
+
+func NewLeaderboardHandler(repo repository.LeaderboardRepository) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		repo: repo,
+	}
+}
+
+func convertToSchemaLeaderboardEntry(entry *model.LeaderboardEntry) *schema.LeaderboardEntry {
+	return &schema.LeaderboardEntry{
+		UserID:      entry.UserID,
+		DisplayName: entry.DisplayName,
+		Score:       entry.Score,
+		Rank:        entry.Rank,
+	}
+}
+
+func bindLeaderboardQuery(c echo.Context) (*schema.GetLeaderboardQuery, model.LeaderboardKind, model.LeaderboardPeriod, error) {
+	var req schema.GetLeaderboardQuery
+	if err := c.Bind(&req); err != nil {
+		return nil, "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	kind, err := model.NewLeaderboardKind(req.Kind)
+	if err != nil {
+		return nil, "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid leaderboard")
+	}
+	period, err := model.NewLeaderboardPeriod(req.Period)
+	if err != nil {
+		return nil, "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid period")
+	}
+	return &req, kind, period, nil
+}
+
+// GetLeaderboard はランキングを上位から返す
+func (h *LeaderboardHandler) GetLeaderboard(c echo.Context) error {
+	req, kind, period, err := bindLeaderboardQuery(c)
+	if err != nil {
+		return err
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultLeaderboardLimit
+	}
+	req.Limit = min(req.Limit, maxLeaderboardLimit)
+	req.Offset = max(req.Offset, 0)
+
+	entries, total, err := h.repo.GetEntries(c.Request().Context(), kind, period, time.Now(), req.Offset, req.Limit)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	res := &schema.GetLeaderboardResponse{
+		Entries: make([]*schema.LeaderboardEntry, 0, len(entries)),
+		Total:   total,
+	}
+	for _, entry := range entries {
+		res.Entries = append(res.Entries, convertToSchemaLeaderboardEntry(entry))
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetMyEntry は自分の順位を返す
+func (h *LeaderboardHandler) GetMyEntry(c echo.Context) error {
+	_, kind, period, err := bindLeaderboardQuery(c)
+	if err != nil {
+		return err
+	}
+
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	entry, err := h.repo.GetEntry(c.Request().Context(), kind, period, time.Now(), userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+	if entry == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "not ranked")
+	}
+
+	return c.JSON(http.StatusOK, convertToSchemaLeaderboardEntry(entry))
+}
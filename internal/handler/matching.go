@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/internal/usecase"
+	"github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+type MatchingHandler struct {
+	mm   *usecase.Matchmaker
+	auth middleware.AuthController
+}
+
+func NewMatchingHandler(mm *usecase.Matchmaker, auth middleware.AuthController) *MatchingHandler {
+	return &MatchingHandler{
+		mm:   mm,
+		auth: auth,
+	}
+}
+
+// Enqueue はマッチングの待ち行列に並ぶ。結果は Wait で受け取る
+func (h *MatchingHandler) Enqueue(c echo.Context) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+	user, err := h.auth.GetUser(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := h.mm.Enqueue(c.Request().Context(), userID, user.DisplayName); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// Wait は long-poll でマッチングの結果を待つ
+// 見つからないまま時間切れになった場合は 204 を返すので、もう一度呼び出す
+func (h *MatchingHandler) Wait(c echo.Context) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	res, err := h.mm.Wait(c.Request().Context(), userID)
+	if errors.Is(err, model.ErrNotInQueue) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+	if res == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return c.JSON(http.StatusOK, schema.MatchingResponse{ID: res.RoomID, OTP: res.OTP})
+}
+
+// Matching は以前のクライアントのために、待ち行列に並ばずに参加できるルームを1つ返す
+func (h *MatchingHandler) Matching(c echo.Context) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	roomID, err := h.mm.FindRoom(c.Request().Context(), userID)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if roomID == "" {
+		return c.JSON(http.StatusNotFound, "no room found")
+	}
+
+	return c.JSON(http.StatusOK, schema.MatchingResponse{ID: roomID})
+}
+
+func (h *MatchingHandler) Cancel(c echo.Context) error {
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	if err := h.mm.Cancel(c.Request().Context(), userID); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/Simo-C3/stego2-server/internal/domain/model"
 	"github.com/Simo-C3/stego2-server/internal/domain/repository"
@@ -11,14 +12,16 @@ import (
 )
 
 type OTPHandler struct {
-	repo repository.OTPRepository
-	auth middleware.AuthController
+	repo     repository.OTPRepository
+	roomRepo repository.RoomRepository
+	auth     middleware.AuthController
 }
 
-func NewOTPHandler(otpRepo repository.OTPRepository, auth middleware.AuthController) *OTPHandler {
+func NewOTPHandler(otpRepo repository.OTPRepository, roomRepo repository.RoomRepository, auth middleware.AuthController) *OTPHandler {
 	return &OTPHandler{
-		repo: otpRepo,
-		auth: auth,
+		repo:     otpRepo,
+		roomRepo: roomRepo,
+		auth:     auth,
 	}
 }
 
@@ -29,13 +32,26 @@ func convertToSchemaOTP(otp *model.OTP) *schema.OTP {
 }
 
 func (h *OTPHandler) GenerateOTP(c echo.Context) error {
+	var req schema.GenerateOTPRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
 	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
 	user, err := h.auth.GetUser(c)
 	if err != nil {
 		c.Logger().Error(err)
 		return err
 	}
 
+	if req.RoomID != "" || req.InviteCode != "" {
+		return h.generateRoomOTP(c, &req, userID, user.DisplayName)
+	}
+
 	otp, err := h.repo.GenerateOTP(c.Request().Context(), userID, user.DisplayName)
 	if err != nil {
 		c.Logger().Error(err)
@@ -44,3 +60,37 @@ func (h *OTPHandler) GenerateOTP(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, convertToSchemaOTP(otp))
 }
+
+// generateRoomOTP は招待コードとパスワードを確認して、そのルームにだけ参加できる OTP を生成する
+func (h *OTPHandler) generateRoomOTP(c echo.Context, req *schema.GenerateOTPRequest, userID, name string) error {
+	ctx := c.Request().Context()
+
+	var room *model.Room
+	var err error
+	if req.InviteCode != "" {
+		room, err = h.roomRepo.GetRoomByInviteCode(ctx, strings.ToUpper(strings.TrimSpace(req.InviteCode)))
+	} else {
+		room, err = h.roomRepo.GetRoomByID(ctx, req.RoomID)
+		// 非公開のルームは ID だけでは参加できない
+		if err == nil && room.Private && room.OwnerID != userID {
+			return echo.NewHTTPError(http.StatusForbidden, "invite code is required")
+		}
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "room not found")
+	}
+
+	if err := room.CheckPassword(req.Password); err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+
+	otp, err := h.repo.GenerateRoomOTP(ctx, room.ID, userID, name)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	res := convertToSchemaOTP(otp)
+	res.RoomID = room.ID
+	return c.JSON(http.StatusOK, res)
+}
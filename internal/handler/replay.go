@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+const (
+	defaultReplayLimit = 20
+	maxReplayLimit     = 100
+	// 再生速度の上限
+	maxReplaySpeed = 16
+)
+
+type ReplayHandler struct {
+	upgrader *websocket.Upgrader
+	repo     repository.ReplayRepository
+	otpRepo  repository.OTPRepository
+}
+
+func NewReplayHandler(repo repository.ReplayRepository, otpRepo repository.OTPRepository) *ReplayHandler {
+	return &ReplayHandler{
+		upgrader: &websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+		repo:    repo,
+		otpRepo: otpRepo,
+	}
+}
+
+func convertToSchemaReplay(replay *model.Replay) *schema.Replay {
+	return &schema.Replay{
+		ID:         replay.ID,
+		RoomID:     replay.RoomID,
+		RoomName:   replay.RoomName,
+		UserIDs:    replay.UserIDs,
+		StartedAt:  replay.StartAt,
+		FinishedAt: replay.FinishAt,
+	}
+}
+
+func convertToSchemaReplayEvent(replay *model.Replay, event *model.ReplayEvent) *schema.ReplayEvent {
+	return &schema.ReplayEvent{
+		At:           event.At - replay.StartAt,
+		From:         event.From,
+		IncludeUsers: event.IncludeUsers,
+		ExcludeUsers: event.ExcludeUsers,
+		Payload:      event.Payload,
+	}
+}
+
+// GetUserReplays はユーザーが参加した最近のリプレイを返す
+// 自分のリプレイしか見られない
+func (h *ReplayHandler) GetUserReplays(c echo.Context) error {
+	var req schema.GetReplaysQuery
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+	if req.UserID != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "forbidden")
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultReplayLimit
+	}
+	req.Limit = min(req.Limit, maxReplayLimit)
+
+	replays, err := h.repo.GetUserReplays(c.Request().Context(), req.UserID, req.Limit)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	res := make([]*schema.Replay, 0, len(replays))
+	for _, replay := range replays {
+		res = append(res, convertToSchemaReplay(replay))
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// GetReplay はリプレイの情報と、参加者に見えていたメッセージをすべて返す
+func (h *ReplayHandler) GetReplay(c echo.Context) error {
+	id := c.Param("id")
+
+	userID, err := middleware.GetUserID(c)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	replay, events, err := h.getReplay(c, id, userID)
+	if err != nil {
+		return err
+	}
+
+	res := &schema.GetReplayResponse{
+		Replay: convertToSchemaReplay(replay),
+		Events: make([]*schema.ReplayEvent, 0, len(events)),
+	}
+	for _, event := range events {
+		res.Events = append(res.Events, convertToSchemaReplayEvent(replay, event))
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=replay-"+id+".json")
+	return c.JSON(http.StatusOK, res)
+}
+
+// StreamReplay はリプレイのメッセージを記録した時刻の間隔で websocket に流す
+// speed を指定すると早送りで再生する
+func (h *ReplayHandler) StreamReplay(c echo.Context) error {
+	var req schema.StreamReplayQuery
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+	if req.Speed <= 0 {
+		req.Speed = 1
+	}
+	req.Speed = min(req.Speed, maxReplaySpeed)
+
+	ctx := c.Request().Context()
+	u, err := h.otpRepo.VerifyOTP(ctx, req.Otp)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid otp")
+	}
+	userID := strings.SplitN(u, ";", 2)[0]
+
+	replay, events, err := h.getReplay(c, req.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	ws, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		c.Logger().Errorf("%+v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to upgrade to websocket")
+	}
+	defer ws.Close()
+
+	// クライアントが接続を閉じたら再生を止める
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	for _, event := range events {
+		e := convertToSchemaReplayEvent(replay, event)
+		wait := time.Duration(float64(e.At)/req.Speed)*time.Millisecond - time.Since(start)
+		select {
+		case <-closed:
+			return nil
+		case <-time.After(wait):
+		}
+
+		if err := ws.WriteJSON(&schema.Base{
+			Type:    schema.TypeReplayEvent,
+			Payload: e,
+		}); err != nil {
+			return nil
+		}
+	}
+
+	return ws.WriteJSON(&schema.Base{
+		Type: schema.TypeReplayEnd,
+	})
+}
+
+// getReplay は試合の参加者だけにリプレイを返す
+// 他の参加者だけに送ったメッセージは取り除く
+func (h *ReplayHandler) getReplay(c echo.Context, id, userID string) (*model.Replay, []*model.ReplayEvent, error) {
+	ctx := c.Request().Context()
+
+	replay, err := h.repo.GetReplay(ctx, id)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, "replay not found")
+	}
+	if !replay.HasParticipant(userID) {
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, "forbidden")
+	}
+
+	events, err := h.repo.GetEvents(ctx, id)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, nil, err
+	}
+
+	visible := make([]*model.ReplayEvent, 0, len(events))
+	for _, event := range events {
+		if event.VisibleTo(userID) {
+			visible = append(visible, event)
+		}
+	}
+
+	return replay, visible, nil
+}
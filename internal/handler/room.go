@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"cmp"
+	"context"
+	"database/sql"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"github.com/Simo-C3/stego2-server/internal/domain/model"
 	"github.com/Simo-C3/stego2-server/internal/domain/repository"
 	"github.com/Simo-C3/stego2-server/internal/schema"
@@ -13,25 +20,34 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	defaultRoomLimit = 20
+	maxRoomLimit     = 100
+)
+
 type RoomHandler struct {
-	upgrader  *websocket.Upgrader
-	wsHandler *WSHandler
-	roomRepo  repository.RoomRepository
-	otpRepo   repository.OTPRepository
-	gameRepo  repository.GameRepository
+	upgrader    *websocket.Upgrader
+	wsHandler   *WSHandler
+	roomRepo    repository.RoomRepository
+	otpRepo     repository.OTPRepository
+	gameRepo    repository.GameRepository
+	sessionRepo repository.SessionRepository
+	matchRepo   repository.MatchmakingRepository
 }
 
-func NewRoomHandler(wsHandler *WSHandler, roomRepo repository.RoomRepository, otpRepo repository.OTPRepository, gameRepo repository.GameRepository) *RoomHandler {
+func NewRoomHandler(wsHandler *WSHandler, roomRepo repository.RoomRepository, otpRepo repository.OTPRepository, gameRepo repository.GameRepository, sessionRepo repository.SessionRepository, matchRepo repository.MatchmakingRepository) *RoomHandler {
 	return &RoomHandler{
 		upgrader: &websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true
 			},
 		},
-		wsHandler: wsHandler,
-		roomRepo:  roomRepo,
-		otpRepo:   otpRepo,
-		gameRepo:  gameRepo,
+		wsHandler:   wsHandler,
+		roomRepo:    roomRepo,
+		otpRepo:     otpRepo,
+		gameRepo:    gameRepo,
+		sessionRepo: sessionRepo,
+		matchRepo:   matchRepo,
 	}
 }
 
@@ -45,6 +61,15 @@ func convertToCreateRoomEntity(room *schema.CreateRoomRequest, uuid string, owne
 		MaxUserNum: room.MaxUserNum,
 		UseCPU:     room.UseCPU,
 		Status:     "pending",
+		Duration:   room.Duration,
+		TeamNum:    room.TeamNum,
+
+		WinCondition: cmp.Or(room.WinCondition, model.WinConditionLastStanding),
+		WinTarget:    room.WinTarget,
+
+		AutoStart: room.AutoStart,
+
+		Private: room.Private,
 	}
 }
 
@@ -58,29 +83,166 @@ func convertToSchemaRoom(room *model.Room) *schema.Room {
 		MaxUserNum: room.MaxUserNum,
 		UseCPU:     room.UseCPU,
 		Status:     room.Status,
+		Duration:   room.Duration,
+		TeamNum:    room.TeamNum,
+
+		WinCondition: room.WinCondition,
+		WinTarget:    room.WinTarget,
+
+		AutoStart: room.AutoStart,
+
+		Private:     room.Private,
+		HasPassword: room.HasPassword(),
+
+		CreatedAt: room.CreatedAt.Unix(),
 	}
 }
 
 func (h *RoomHandler) GetRooms(c echo.Context) error {
-	rooms, err := h.roomRepo.GetRooms(c.Request().Context())
+	var req schema.GetRoomsQuery
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request")
+	}
+
+	filter, err := convertToRoomFilter(&req)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	sort, err := model.NewRoomSort(req.Sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid sort")
+	}
+	cursor, err := model.DecodeRoomCursor(req.Cursor, sort)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid cursor")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultRoomLimit
+	}
+	limit = min(limit, maxRoomLimit)
+
+	// 次のページがあるかを知るために 1 件多く取得する
+	listings, total, err := h.listRooms(c.Request().Context(), filter, sort, cursor, limit+1)
 	if err != nil {
 		c.Logger().Error(err)
 		return err
 	}
 
-	res := make([]*schema.Room, 0, len(rooms))
-	for _, room := range rooms {
-		res = append(res, convertToSchemaRoom(room))
+	res := schema.GetRoomsResponse{
+		Rooms: make([]*schema.Room, 0, len(listings)),
+		Total: total,
+	}
+	if len(listings) > limit {
+		listings = listings[:limit]
+		res.NextCursor = model.NewRoomCursor(sort, listings[limit-1]).Encode()
+	}
+	for _, l := range listings {
+		room := convertToSchemaRoom(l.Room)
+		room.UserNum = l.UserNum
+		res.Rooms = append(res.Rooms, room)
 	}
 
 	return c.JSON(http.StatusOK, res)
 }
 
+func convertToRoomFilter(req *schema.GetRoomsQuery) (*model.RoomFilter, error) {
+	filter := &model.RoomFilter{
+		Name:     strings.TrimSpace(req.Name),
+		HasSpace: req.HasSpace,
+	}
+
+	if req.Status != "" {
+		for _, status := range strings.Split(req.Status, ",") {
+			switch status {
+			case model.RoomStatusPending, model.RoomStatusPlaying, model.RoomStatusFinish:
+				filter.Statuses = append(filter.Statuses, status)
+			default:
+				return nil, errors.New("invalid status")
+			}
+		}
+	}
+
+	if req.CPU != "" {
+		useCPU, err := strconv.ParseBool(req.CPU)
+		if err != nil {
+			return nil, errors.New("invalid cpu")
+		}
+		filter.UseCPU = &useCPU
+	}
+
+	return filter, nil
+}
+
+// listRooms はカーソルの続きから n 件のルームを現在の参加人数と一緒に返す
+// 参加人数は Redis にしかないので、参加人数で並べ替え・絞り込みをする場合は全件取得してから行う
+// (参加人数は変わるので、ページをまたぐと重複したり抜けたりすることがある)
+func (h *RoomHandler) listRooms(ctx context.Context, filter *model.RoomFilter, sort model.RoomSort, cursor *model.RoomCursor, n int) ([]*model.RoomListing, int, error) {
+	if sort == model.RoomSortCreated && !filter.HasSpace {
+		rooms, total, err := h.roomRepo.GetRooms(ctx, filter, cursor, n)
+		if err != nil {
+			return nil, 0, err
+		}
+		listings, err := h.convertToRoomListings(ctx, rooms)
+		return listings, total, err
+	}
+
+	rooms, _, err := h.roomRepo.GetRooms(ctx, filter, nil, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	listings, err := h.convertToRoomListings(ctx, rooms)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.HasSpace {
+		listings = slices.DeleteFunc(listings, func(l *model.RoomListing) bool {
+			return !l.HasSpace()
+		})
+	}
+	total := len(listings)
+
+	slices.SortFunc(listings, func(a, b *model.RoomListing) int {
+		return model.CompareRoomListings(sort, a, b)
+	})
+	if cursor != nil {
+		listings = slices.DeleteFunc(listings, func(l *model.RoomListing) bool {
+			return !cursor.IsAfter(l)
+		})
+	}
+
+	return listings[:min(n, len(listings))], total, nil
+}
+
+func (h *RoomHandler) convertToRoomListings(ctx context.Context, rooms []*model.Room) ([]*model.RoomListing, error) {
+	ids := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		ids = append(ids, room.ID)
+	}
+	nums, err := h.gameRepo.GetUserNums(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	listings := make([]*model.RoomListing, 0, len(rooms))
+	for _, room := range rooms {
+		listings = append(listings, &model.RoomListing{Room: room, UserNum: nums[room.ID]})
+	}
+	return listings, nil
+}
+
 func (h *RoomHandler) CreateRoom(c echo.Context) error {
 	req := new(schema.CreateRoomRequest)
 	if err := c.Bind(req); err != nil {
 		return err
 	}
+	if req.Duration < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid duration")
+	}
+	if req.TeamNum < 0 || req.TeamNum == 1 || req.TeamNum > req.MaxUserNum {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid team num")
+	}
 
 	uuid, err := uuid.GenerateUUIDv7()
 	if err != nil {
@@ -95,6 +257,21 @@ func (h *RoomHandler) CreateRoom(c echo.Context) error {
 	}
 
 	createRoomRequest := convertToCreateRoomEntity(req, uuid, ownerID)
+	if err := model.ValidateWinCondition(createRoomRequest); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := createRoomRequest.SetPassword(req.Password); err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+	if createRoomRequest.Private {
+		code, err := h.newInviteCode(c)
+		if err != nil {
+			c.Logger().Error(err)
+			return err
+		}
+		createRoomRequest.InviteCode = code
+	}
 
 	roomID, err := h.roomRepo.CreateRoom(c.Request().Context(), createRoomRequest)
 	if err != nil {
@@ -102,21 +279,42 @@ func (h *RoomHandler) CreateRoom(c echo.Context) error {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, schema.CreateRoomResponse{RoomID: roomID})
+	return c.JSON(http.StatusOK, schema.CreateRoomResponse{RoomID: roomID, InviteCode: createRoomRequest.InviteCode})
 }
 
-func (h *RoomHandler) Matching(c echo.Context) error {
-	roomID, err := h.roomRepo.Matching(c.Request().Context())
-	if err != nil {
-		c.Logger().Error(err)
-		return err
+// newInviteCode は終了していないルームと重ならない招待コードを生成する
+func (h *RoomHandler) newInviteCode(c echo.Context) (string, error) {
+	for range 5 {
+		code, err := model.NewInviteCode()
+		if err != nil {
+			return "", err
+		}
+		_, err = h.roomRepo.GetRoomByInviteCode(c.Request().Context(), code)
+		if errors.Is(err, sql.ErrNoRows) {
+			return code, nil
+		}
+		if err != nil {
+			return "", err
+		}
 	}
+	return "", errors.New("failed to generate invite code")
+}
 
-	if roomID == "" {
-		return c.JSON(http.StatusNotFound, "no room found")
+// reservedSeats はマッチングで他のユーザーのために確保されている席数を返す
+// userID が確保した席は、本人が座れるように数えない
+func (h *RoomHandler) reservedSeats(ctx context.Context, roomID, userID string) (int, error) {
+	reserved, err := h.matchRepo.GetReserved(ctx, roomID)
+	if err != nil {
+		return 0, err
 	}
-
-	return c.JSON(http.StatusOK, schema.MatchingResponse{ID: roomID})
+	held, err := h.matchRepo.IsReserved(ctx, roomID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if held {
+		reserved--
+	}
+	return max(0, reserved), nil
 }
 
 func (h *RoomHandler) JoinRoom(c echo.Context) error {
@@ -127,6 +325,16 @@ func (h *RoomHandler) JoinRoom(c echo.Context) error {
 	}
 
 	ctx := c.Request().Context()
+	if req.Reconnect != "" {
+		return h.reconnect(c, req.ID, req.Reconnect)
+	}
+
+	// OTP を使うと消えるので先に取り出しておく
+	otpRoomID, err := h.otpRepo.GetOTPRoom(ctx, req.Otp)
+	if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to verify otp")
+	}
 	u, err := h.otpRepo.VerifyOTP(ctx, req.Otp)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid otp")
@@ -136,38 +344,66 @@ func (h *RoomHandler) JoinRoom(c echo.Context) error {
 	displayName := us[1]
 
 	game, err := h.gameRepo.GetGameByID(ctx, req.ID)
-	if err != nil {
-		room, err := h.roomRepo.GetRoomByID(ctx, req.ID)
-		if err != nil {
-			c.Logger().Error(err)
-			return echo.NewHTTPError(http.StatusNotFound, "room not found")
+	if errors.Is(err, model.ErrGameNotFound) {
+		if game, err = h.createGame(c, req.ID); err != nil {
+			return err
 		}
+	} else if err != nil {
+		c.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get game")
+	}
 
-		if room.Status != "pending" {
-			return echo.NewHTTPError(http.StatusForbidden, "room is not pending")
-		}
+	if game.IsBanned(userID) {
+		return echo.NewHTTPError(http.StatusForbidden, "you are banned from this room")
+	}
+	if !canEnter(game, userID, req.ID, otpRoomID) {
+		return echo.NewHTTPError(http.StatusForbidden, "invite code or password is required")
+	}
 
-		game = model.NewGame(req.ID, model.GameStatusPending, room)
-		if err := h.gameRepo.UpdateGame(ctx, game); err != nil {
-			c.Logger().Error(err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "failed to update game")
-		}
+	if req.Spectate {
+		return h.spectate(c, req.ID, userID)
 	}
 
-	user := model.NewUser(userID, displayName)
-	if err := h.gameRepo.UpdateUser(ctx, user); err != nil {
+	reserved, err := h.reservedSeats(ctx, req.ID, userID)
+	if err != nil {
 		c.Logger().Error(err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update user")
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get reserved seats")
 	}
 
-	if err := game.AddUser(user); err != nil {
+	// 読み込んでから書き込むまでの間の変更を消さないように、EditGame の中で追加する
+	// 既に参加しているユーザーは状態を引き継ぐ
+	newUser := model.NewUser(userID, displayName)
+	created := false
+	err = h.gameRepo.EditGame(ctx, req.ID, func(g *model.Game) error {
+		user, ok := g.Users[userID]
+		created = !ok
+		if !ok {
+			if !g.HasVacancy(reserved) {
+				return model.ErrMaxUserNum
+			}
+			user = newUser
+		}
+		return g.AddUser(user)
+	})
+	if errors.Is(err, model.ErrBanned) {
+		return echo.NewHTTPError(http.StatusForbidden, "you are banned from this room")
+	}
+	if errors.Is(err, model.ErrMaxUserNum) {
+		return echo.NewHTTPError(http.StatusForbidden, "room is full")
+	}
+	if err != nil {
 		c.Logger().Error(err)
 		return echo.NewHTTPError(http.StatusForbidden, "failed to add user")
 	}
-
-	if err := h.gameRepo.UpdateGame(ctx, game); err != nil {
+	if created {
+		if err := h.gameRepo.UpdateUser(ctx, newUser); err != nil {
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to update user")
+		}
+	}
+	// マッチングで確保していた席は参加した人数に数えられるので解放する
+	if err := h.matchRepo.Release(ctx, req.ID, userID); err != nil {
 		c.Logger().Error(err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update game")
 	}
 
 	// Upgrade to websocket
@@ -182,3 +418,90 @@ func (h *RoomHandler) JoinRoom(c echo.Context) error {
 
 	return nil
 }
+
+// createGame は最初の参加者が来たときに待機中のルームのゲームを作る
+// 同時に作られた場合は先に作られた方を返す
+func (h *RoomHandler) createGame(c echo.Context, roomID string) (*model.Game, error) {
+	ctx := c.Request().Context()
+	room, err := h.roomRepo.GetRoomByID(ctx, roomID)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusNotFound, "room not found")
+	}
+
+	if room.Status != model.RoomStatusPending {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "room is not pending")
+	}
+
+	game := model.NewGame(roomID, model.GameStatusPending, room)
+	ok, err := h.gameRepo.CreateGame(ctx, game)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to update game")
+	}
+	if ok {
+		return game, nil
+	}
+
+	game, err = h.gameRepo.GetGameByID(ctx, roomID)
+	if err != nil {
+		c.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get game")
+	}
+	return game, nil
+}
+
+// canEnter はルーム用の OTP が必要なルームに入れるかを確認する
+// 既に参加しているユーザーとオーナーは確認しない
+func canEnter(game *model.Game, userID, roomID, otpRoomID string) bool {
+	if otpRoomID != "" {
+		return otpRoomID == roomID
+	}
+	if !game.BaseRoom.IsRestricted() {
+		return true
+	}
+	_, joined := game.Users[userID]
+	return joined || game.BaseRoom.OwnerID == userID
+}
+
+// spectate は観戦者として websocket に接続する
+func (h *RoomHandler) spectate(c echo.Context, roomID, userID string) error {
+	ws, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		c.Logger().Errorf("%+v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to upgrade to websocket")
+	}
+	defer ws.Close()
+
+	h.wsHandler.HandleSpectator(c.Request().Context(), ws, roomID, userID)
+
+	return nil
+}
+
+// reconnect は再接続用のトークンで websocket に接続し直す
+func (h *RoomHandler) reconnect(c echo.Context, roomID, token string) error {
+	ctx := c.Request().Context()
+	tokenRoomID, userID, err := h.sessionRepo.VerifyReconnectToken(ctx, token)
+	if err != nil || tokenRoomID != roomID {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid reconnect token")
+	}
+
+	game, err := h.gameRepo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "game not found")
+	}
+	if _, ok := game.Users[userID]; !ok {
+		return echo.NewHTTPError(http.StatusForbidden, "user is not in the game")
+	}
+
+	ws, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		c.Logger().Errorf("%+v", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to upgrade to websocket")
+	}
+	defer ws.Close()
+
+	h.wsHandler.HandleReconnect(ctx, ws, roomID, userID)
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+const (
+	// 成績に表示する得意なレベルの数
+	favoriteLevelNum = 3
+	// レーティングと一緒に返す履歴の数
+	ratingHistoryNum = 20
+)
+
+type UserHandler struct {
+	statsRepo  repository.StatsRepository
+	ratingRepo repository.RatingRepository
+}
+
+func NewUserHandler(statsRepo repository.StatsRepository, ratingRepo repository.RatingRepository) *UserHandler {
+	return &UserHandler{
+		statsRepo:  statsRepo,
+		ratingRepo: ratingRepo,
+	}
+}
+
+func convertToSchemaUserStats(stats *model.UserStats) *schema.UserStats {
+	return &schema.UserStats{
+		UserID:         stats.UserID,
+		GamesPlayed:    stats.GamesPlayed,
+		Wins:           stats.Wins,
+		AverageWPM:     stats.AverageWPM(),
+		BestWPM:        stats.BestWPM,
+		Accuracy:       stats.Accuracy(),
+		TypedChars:     stats.TypedChars,
+		FavoriteLevels: stats.FavoriteLevels(favoriteLevelNum),
+	}
+}
+
+// GetUserStats はユーザーの通算のタイピングの成績を返す
+func (h *UserHandler) GetUserStats(c echo.Context) error {
+	stats, err := h.statsRepo.GetUserStats(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, convertToSchemaUserStats(stats))
+}
+
+func convertToSchemaRating(rating *model.Rating, history []*model.RatingHistory) *schema.Rating {
+	res := &schema.Rating{
+		UserID:      rating.UserID,
+		Rating:      rating.Rating,
+		RD:          rating.RD,
+		Volatility:  rating.Volatility,
+		GamesPlayed: rating.GamesPlayed,
+		History:     make([]*schema.RatingHistory, 0, len(history)),
+	}
+	for _, h := range history {
+		res.History = append(res.History, &schema.RatingHistory{
+			GameID:    h.MatchID,
+			Before:    h.Before,
+			After:     h.After,
+			RD:        h.RD,
+			CreatedAt: h.CreatedAt.Unix(),
+		})
+	}
+	return res
+}
+
+// GetUserRating はユーザーのレーティングと最近の履歴を返す
+func (h *UserHandler) GetUserRating(c echo.Context) error {
+	ctx := c.Request().Context()
+	userID := c.Param("id")
+
+	ratings, err := h.ratingRepo.GetRatings(ctx, []string{userID})
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+	history, err := h.ratingRepo.GetHistory(ctx, userID, ratingHistoryNum)
+	if err != nil {
+		c.Logger().Error(err)
+		return err
+	}
+
+	return c.JSON(http.StatusOK, convertToSchemaRating(ratings[userID], history))
+}
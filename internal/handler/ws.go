@@ -8,6 +8,7 @@ import (
 	"github.com/Simo-C3/stego2-server/internal/schema"
 	"github.com/Simo-C3/stego2-server/internal/usecase"
 	"github.com/Simo-C3/stego2-server/pkg/logger"
+	"github.com/Simo-C3/stego2-server/pkg/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -28,14 +29,56 @@ func (h *WSHandler) Handle(ctx context.Context, ws *websocket.Conn, roomID, user
 	defer close(errCh)
 
 	h.msgSender.Register(userID, ws, errCh)
-	defer h.msgSender.Unregister(userID)
+	defer h.msgSender.UnregisterConn(userID, ws)
 
 	logger := logger.New()
 
-	if err := h.gm.Join(ctx, roomID, userID); err != nil {
+	connID, err := uuid.GenerateUUIDv7()
+	if err != nil {
 		logger.LogErrorWithStack(ctx, err)
+		return
 	}
 
+	if err := h.gm.Join(ctx, roomID, userID, connID); err != nil {
+		logger.LogErrorWithStack(ctx, err)
+	}
+
+	h.serve(ctx, ws, roomID, userID, connID)
+}
+
+// HandleReconnect は切断されたユーザーの再接続を扱う
+func (h *WSHandler) HandleReconnect(ctx context.Context, ws *websocket.Conn, roomID, userID string) {
+	errCh := make(chan error)
+	defer close(errCh)
+
+	h.msgSender.Register(userID, ws, errCh)
+	defer h.msgSender.UnregisterConn(userID, ws)
+
+	logger := logger.New()
+
+	connID, err := uuid.GenerateUUIDv7()
+	if err != nil {
+		logger.LogErrorWithStack(ctx, err)
+		return
+	}
+
+	if err := h.gm.Reconnect(ctx, roomID, userID, connID); err != nil {
+		logger.LogErrorWithStack(ctx, err)
+		return
+	}
+
+	h.serve(ctx, ws, roomID, userID, connID)
+}
+
+// serve は接続が切れるまでクライアントからのメッセージを処理する
+func (h *WSHandler) serve(ctx context.Context, ws *websocket.Conn, roomID, userID, connID string) {
+	logger := logger.New()
+	defer func() {
+		if err := h.gm.Disconnect(context.Background(), roomID, userID, connID); err != nil {
+			logger.LogErrorWithStack(ctx, err)
+		}
+	}()
+
 	for {
 		_, p, err := ws.ReadMessage()
 		if err != nil {
@@ -48,6 +91,7 @@ func (h *WSHandler) Handle(ctx context.Context, ws *websocket.Conn, roomID, user
 			logger.LogErrorWithStack(ctx, err)
 			break
 		}
+		h.gm.RecordInput(ctx, roomID, userID, p)
 
 		switch msg.Type {
 		case schema.TypeTypingKey:
@@ -66,6 +110,62 @@ func (h *WSHandler) Handle(ctx context.Context, ws *websocket.Conn, roomID, user
 			if err := h.gm.FinCurrentSeq(ctx, roomID, userID, req.Payload.Cause); err != nil {
 				logger.LogErrorWithStack(ctx, err)
 			}
+		case schema.TypeChangeTarget:
+			var req schema.ChangeTarget
+			if err := json.Unmarshal(p, &req); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+			if err := h.gm.ChangeTarget(ctx, roomID, userID, req.Payload.Mode, req.Payload.UserID); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+		case schema.TypeAssignTeam:
+			var req schema.AssignTeam
+			if err := json.Unmarshal(p, &req); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+			if err := h.gm.AssignTeam(ctx, roomID, userID, req.Payload.UserID, req.Payload.Team); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+		case schema.TypeReady:
+			var req schema.Ready
+			if err := json.Unmarshal(p, &req); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+			if err := h.gm.SetReady(ctx, roomID, userID, req.Payload.Ready); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+		case schema.TypeCancelCountdown:
+			if err := h.gm.CancelCountdown(ctx, roomID, userID); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+		case schema.TypeTransferOwner:
+			var req schema.TransferOwner
+			if err := json.Unmarshal(p, &req); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+			if err := h.gm.TransferOwner(ctx, roomID, userID, req.Payload.UserID); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+		case schema.TypeKick, schema.TypeBan:
+			var req schema.Moderate
+			if err := json.Unmarshal(p, &req); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+			moderate := h.gm.Kick
+			if req.Type == schema.TypeBan {
+				moderate = h.gm.Ban
+			}
+			if err := moderate(ctx, roomID, userID, req.Payload.UserID); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+		case schema.TypeLeave:
+			if err := h.gm.Leave(ctx, roomID, userID); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+		case schema.TypeRematch:
+			if err := h.gm.Rematch(ctx, roomID, userID); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
 		case schema.TypeStartGame:
 			if err := h.gm.StartGame(ctx, roomID, userID); err != nil {
 				logger.LogErrorWithStack(ctx, err)
@@ -74,6 +174,38 @@ func (h *WSHandler) Handle(ctx context.Context, ws *websocket.Conn, roomID, user
 	}
 }
 
+// HandleSpectator は観戦者の接続を扱う
+// 観戦者からのゲームの入力は受け付けない
+func (h *WSHandler) HandleSpectator(ctx context.Context, ws *websocket.Conn, roomID, userID string) {
+	errCh := make(chan error)
+	defer close(errCh)
+
+	h.msgSender.Register(userID, ws, errCh)
+	defer h.msgSender.UnregisterConn(userID, ws)
+
+	logger := logger.New()
+
+	if err := h.gm.Spectate(ctx, roomID, userID); err != nil {
+		logger.LogErrorWithStack(ctx, err)
+		return
+	}
+	defer func() {
+		if err := h.gm.LeaveSpectator(context.Background(), roomID, userID); err != nil {
+			logger.LogErrorWithStack(ctx, err)
+		}
+	}()
+
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
+
 func (h *WSHandler) SubscribeHandle(ctx context.Context, topic string) {
 	h.gm.SubscribeMessage(ctx, topic)
 }
+
+func (h *WSHandler) SubscribeTimerHandle(ctx context.Context) {
+	h.gm.SubscribeTimer(ctx)
+}
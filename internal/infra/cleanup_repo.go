@@ -0,0 +1,53 @@
+package infra
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/pkg/database"
+)
+
+type CleanupModel struct {
+	bun.BaseModel `bun:"table:cleanups"`
+
+	ID        int64     `bun:",pk,autoincrement"`
+	Kind      string    `bun:"kind"`
+	TargetID  string    `bun:"target_id"`
+	Detail    string    `bun:"detail"`
+	CreatedAt time.Time `bun:"created_at"`
+}
+
+type cleanupRepository struct {
+	db *database.DB
+}
+
+func NewCleanupRepository(db *database.DB) repository.CleanupRepository {
+	return &cleanupRepository{
+		db: db,
+	}
+}
+
+// SaveCleanups implements repository.CleanupRepository.
+func (r *cleanupRepository) SaveCleanups(ctx context.Context, cleanups []*model.Cleanup) error {
+	if len(cleanups) == 0 {
+		return nil
+	}
+
+	models := make([]*CleanupModel, 0, len(cleanups))
+	for _, c := range cleanups {
+		models = append(models, &CleanupModel{
+			Kind:      string(c.Kind),
+			TargetID:  c.TargetID,
+			Detail:    c.Detail,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+
+	_, err := r.db.NewInsert().Model(&models).Exec(ctx)
+	return errors.WithStack(err)
+}
@@ -3,6 +3,7 @@ package infra
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"github.com/Simo-C3/stego2-server/internal/domain/model"
@@ -13,6 +14,27 @@ import (
 
 const RedisGameKey string = "game"
 
+const (
+	// 最後に書き込んだ時刻順のゲームとユーザーの一覧 (キーの名前から区別できないため)
+	RedisGameIndexKey string = "index:game"
+	RedisUserIndexKey string = "index:user"
+	RedisLockKey      string = "lock:"
+)
+
+// ゲームとユーザーは最後に書き込んでから gameTTL で消える
+const gameTTL = 30 * time.Minute
+
+// 一覧の時刻を確かめてからユーザーを消す
+var deleteStaleUserScript = redis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[2], ARGV[1])
+if not score or tonumber(score) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+redis.call("ZREM", KEYS[2], ARGV[1])
+return 1
+`)
+
 type gameRepository struct {
 	redis *redis.Client
 }
@@ -26,6 +48,9 @@ func NewGameRepository(redis *redis.Client) repository.GameRepository {
 // GetGameByID implements repository.GameRepository.
 func (g *gameRepository) GetGameByID(ctx context.Context, id string) (*model.Game, error) {
 	data, err := g.redis.Get(ctx, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errors.WithStack(model.ErrGameNotFound)
+	}
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -38,18 +63,69 @@ func (g *gameRepository) GetGameByID(ctx context.Context, id string) (*model.Gam
 	return &game, nil
 }
 
-// UpdateGame implements repository.GameRepository.
-func (g *gameRepository) UpdateGame(ctx context.Context, game *model.Game) error {
+// GetUserNums implements repository.GameRepository.
+func (g *gameRepository) GetUserNums(ctx context.Context, ids []string) (map[string]int, error) {
+	nums := make(map[string]int, len(ids))
+	if len(ids) == 0 {
+		return nums, nil
+	}
+
+	res, err := g.redis.MGet(ctx, ids...).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for i, v := range res {
+		data, ok := v.(string)
+		if !ok {
+			nums[ids[i]] = 0
+			continue
+		}
+
+		var game model.Game
+		if err := json.Unmarshal([]byte(data), &game); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		nums[ids[i]] = len(game.Users)
+	}
+
+	return nums, nil
+}
+
+// CreateGame implements repository.GameRepository.
+func (g *gameRepository) CreateGame(ctx context.Context, game *model.Game) (bool, error) {
 	data, err := json.Marshal(game)
 	if err != nil {
-		return errors.WithStack(err)
+		return false, errors.WithStack(err)
 	}
 
-	if err := g.redis.Set(ctx, game.ID, data, 30*time.Minute).Err(); err != nil {
+	ok, err := g.redis.SetNX(ctx, game.ID, data, gameTTL).Result()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if err := g.redis.ZAdd(ctx, RedisGameIndexKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: game.ID}).Err(); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// UpdateGame implements repository.GameRepository.
+func (g *gameRepository) UpdateGame(ctx context.Context, game *model.Game) error {
+	data, err := json.Marshal(game)
+	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	return nil
+	_, err = g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, game.ID, data, gameTTL)
+		pipe.ZAdd(ctx, RedisGameIndexKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: game.ID})
+		return nil
+	})
+	return errors.WithStack(err)
 }
 
 // GetUserByID implements repository.GameRepository.
@@ -73,29 +149,113 @@ func (g *gameRepository) UpdateUser(ctx context.Context, user *model.User) error
 		return errors.WithStack(err)
 	}
 
-	if err := g.redis.Set(ctx, user.ID, data, 30*time.Minute).Err(); err != nil {
-		return errors.WithStack(err)
-	}
-
-	return nil
+	_, err = g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, user.ID, data, gameTTL)
+		pipe.ZAdd(ctx, RedisUserIndexKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: user.ID})
+		return nil
+	})
+	return errors.WithStack(err)
 }
 
 // DeleteGame implements repository.GameRepository.
 func (g *gameRepository) DeleteGame(ctx context.Context, id string) error {
-	if err := g.redis.Del(ctx, id).Err(); err != nil {
-		return errors.WithStack(err)
-	}
-
-	return nil
+	_, err := g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, id)
+		pipe.ZRem(ctx, RedisGameIndexKey, id)
+		return nil
+	})
+	return errors.WithStack(err)
 }
 
 // DeleteUser implements repository.GameRepository.
 func (g *gameRepository) DeleteUser(ctx context.Context, id string) error {
-	if err := g.redis.Del(ctx, id).Err(); err != nil {
-		return errors.WithStack(err)
+	_, err := g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, id)
+		pipe.ZRem(ctx, RedisUserIndexKey, id)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+func (g *gameRepository) DeleteStaleUser(ctx context.Context, id string, updatedBefore time.Time) (bool, error) {
+	n, err := deleteStaleUserScript.Run(ctx, g.redis, []string{id, RedisUserIndexKey}, id, updatedBefore.UnixMilli()).Int()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
+
+// GetGames implements repository.GameRepository.
+func (g *gameRepository) GetGames(ctx context.Context, ids []string) (map[string]*model.Game, error) {
+	games := make(map[string]*model.Game, len(ids))
+	if len(ids) == 0 {
+		return games, nil
+	}
+
+	res, err := g.redis.MGet(ctx, ids...).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
-	return nil
+	for i, v := range res {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		var game model.Game
+		if err := json.Unmarshal([]byte(data), &game); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		games[ids[i]] = &game
+	}
+
+	return games, nil
+}
+
+// GetGameIDs implements repository.GameRepository.
+func (g *gameRepository) GetGameIDs(ctx context.Context) ([]string, error) {
+	ids, err := g.redis.ZRange(ctx, RedisGameIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return ids, nil
+}
+
+// GetUserIDs implements repository.GameRepository.
+func (g *gameRepository) GetUserIDs(ctx context.Context, updatedBefore time.Time) ([]string, error) {
+	ids, err := g.redis.ZRangeByScore(ctx, RedisUserIndexKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(updatedBefore.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return ids, nil
+}
+
+// PruneIndex implements repository.GameRepository.
+func (g *gameRepository) PruneIndex(ctx context.Context, now time.Time) error {
+	expired := "(" + strconv.FormatInt(now.Add(-gameTTL).UnixMilli(), 10)
+	_, err := g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, RedisGameIndexKey, "-inf", expired)
+		pipe.ZRemRangeByScore(ctx, RedisUserIndexKey, "-inf", expired)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// Lock implements repository.GameRepository.
+func (g *gameRepository) Lock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	ok, err := g.redis.SetNX(ctx, RedisLockKey+name, 1, ttl).Result()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return ok, nil
 }
 
 const MaxRetries = 1000
@@ -121,15 +281,17 @@ func (g *gameRepository) EditUser(ctx context.Context, userID string, fn func(*m
 		if err != nil {
 			return errors.WithStack(err)
 		}
-		if err := tx.Set(ctx, userID, data, 30*time.Minute).Err(); err != nil {
-			return errors.WithStack(err)
-		}
-		return nil
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, userID, data, gameTTL)
+			pipe.ZAdd(ctx, RedisUserIndexKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: userID})
+			return nil
+		})
+		return errors.WithStack(err)
 	}
 
 	for range MaxRetries {
 		err := g.redis.Watch(ctx, txf, userID)
-		if err != nil {
+		if err == nil {
 			return nil
 		}
 
@@ -137,7 +299,7 @@ func (g *gameRepository) EditUser(ctx context.Context, userID string, fn func(*m
 			continue
 		}
 
-		return errors.WithStack(err)
+		return err
 	}
 
 	return errors.New("EditUser reached maximum number of retries")
@@ -146,6 +308,9 @@ func (g *gameRepository) EditUser(ctx context.Context, userID string, fn func(*m
 func (g *gameRepository) EditGame(ctx context.Context, gameID string, fn func(*model.Game) error) error {
 	txf := func(tx *redis.Tx) error {
 		b, err := tx.Get(ctx, gameID).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return errors.WithStack(model.ErrGameNotFound)
+		}
 		if err != nil {
 			return errors.WithStack(err)
 		}
@@ -164,15 +329,17 @@ func (g *gameRepository) EditGame(ctx context.Context, gameID string, fn func(*m
 		if err != nil {
 			return errors.WithStack(err)
 		}
-		if err := tx.Set(ctx, gameID, data, 30*time.Minute).Err(); err != nil {
-			return errors.WithStack(err)
-		}
-		return nil
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, gameID, data, gameTTL)
+			pipe.ZAdd(ctx, RedisGameIndexKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: gameID})
+			return nil
+		})
+		return errors.WithStack(err)
 	}
 
 	for range MaxRetries {
 		err := g.redis.Watch(ctx, txf, gameID)
-		if err != nil {
+		if err == nil {
 			return nil
 		}
 
@@ -180,7 +347,7 @@ func (g *gameRepository) EditGame(ctx context.Context, gameID string, fn func(*m
 			continue
 		}
 
-		return errors.WithStack(err)
+		return err
 	}
 
 	return errors.New("EditGame reached maximum number of retries")
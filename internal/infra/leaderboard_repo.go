@@ -0,0 +1,156 @@
+package infra
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+)
+
+const (
+	RedisLeaderboardKey     string = "leaderboard:"
+	RedisLeaderboardNameKey string = "leaderboard:names"
+)
+
+// 期間が終わったランキングもしばらくは残しておく
+const leaderboardRetention = 24 * time.Hour
+
+type leaderboardRepository struct {
+	redis *redis.Client
+}
+
+func NewLeaderboardRepository(redis *redis.Client) repository.LeaderboardRepository {
+	return &leaderboardRepository{
+		redis: redis,
+	}
+}
+
+// leaderboardKey は集計期間ごとのランキングのキーと期限を返す
+func leaderboardKey(kind model.LeaderboardKind, period model.LeaderboardPeriod, now time.Time) (string, time.Time) {
+	name, end := period.Window(now)
+	key := RedisLeaderboardKey + string(kind) + ":" + string(period)
+	if name != "" {
+		key += ":" + name
+	}
+	if end.IsZero() {
+		return key, end
+	}
+	return key, end.Add(leaderboardRetention)
+}
+
+// update は全ての集計期間のランキングに fn を適用する
+func (r *leaderboardRepository) update(ctx context.Context, kind model.LeaderboardKind, userID, name string, now time.Time, fn func(pipe redis.Pipeliner, key string)) error {
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, period := range model.LeaderboardPeriods {
+			key, expireAt := leaderboardKey(kind, period, now)
+			fn(pipe, key)
+			if !expireAt.IsZero() {
+				pipe.ExpireAt(ctx, key, expireAt)
+			}
+		}
+		pipe.HSet(ctx, RedisLeaderboardNameKey, userID, name)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// IncrScore implements repository.LeaderboardRepository.
+func (r *leaderboardRepository) IncrScore(ctx context.Context, kind model.LeaderboardKind, userID, name string, by float64, now time.Time) error {
+	return r.update(ctx, kind, userID, name, now, func(pipe redis.Pipeliner, key string) {
+		pipe.ZIncrBy(ctx, key, by, userID)
+	})
+}
+
+// MaxScore implements repository.LeaderboardRepository.
+func (r *leaderboardRepository) MaxScore(ctx context.Context, kind model.LeaderboardKind, userID, name string, score float64, now time.Time) error {
+	return r.update(ctx, kind, userID, name, now, func(pipe redis.Pipeliner, key string) {
+		pipe.ZAddGT(ctx, key, redis.Z{Score: score, Member: userID})
+	})
+}
+
+// SetScore implements repository.LeaderboardRepository.
+func (r *leaderboardRepository) SetScore(ctx context.Context, kind model.LeaderboardKind, userID, name string, score float64, now time.Time) error {
+	return r.update(ctx, kind, userID, name, now, func(pipe redis.Pipeliner, key string) {
+		pipe.ZAdd(ctx, key, redis.Z{Score: score, Member: userID})
+	})
+}
+
+// GetEntries implements repository.LeaderboardRepository.
+func (r *leaderboardRepository) GetEntries(ctx context.Context, kind model.LeaderboardKind, period model.LeaderboardPeriod, now time.Time, offset, limit int) ([]*model.LeaderboardEntry, int, error) {
+	key, _ := leaderboardKey(kind, period, now)
+
+	total, err := r.redis.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	zs, err := r.redis.ZRevRangeWithScores(ctx, key, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+
+	entries := make([]*model.LeaderboardEntry, 0, len(zs))
+	ids := make([]string, 0, len(zs))
+	for i, z := range zs {
+		id, _ := z.Member.(string)
+		ids = append(ids, id)
+		entries = append(entries, &model.LeaderboardEntry{
+			UserID: id,
+			Score:  z.Score,
+			Rank:   offset + i + 1,
+		})
+	}
+	if err := r.fillNames(ctx, entries, ids); err != nil {
+		return nil, 0, err
+	}
+
+	return entries, int(total), nil
+}
+
+// GetEntry implements repository.LeaderboardRepository.
+func (r *leaderboardRepository) GetEntry(ctx context.Context, kind model.LeaderboardKind, period model.LeaderboardPeriod, now time.Time, userID string) (*model.LeaderboardEntry, error) {
+	key, _ := leaderboardKey(kind, period, now)
+
+	rank, err := r.redis.ZRevRank(ctx, key, userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	score, err := r.redis.ZScore(ctx, key, userID).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	entry := &model.LeaderboardEntry{
+		UserID: userID,
+		Score:  score,
+		Rank:   int(rank) + 1,
+	}
+	if err := r.fillNames(ctx, []*model.LeaderboardEntry{entry}, []string{userID}); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *leaderboardRepository) fillNames(ctx context.Context, entries []*model.LeaderboardEntry, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	names, err := r.redis.HMGet(ctx, RedisLeaderboardNameKey, ids...).Result()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i, name := range names {
+		if s, ok := name.(string); ok {
+			entries[i].DisplayName = s
+		}
+	}
+	return nil
+}
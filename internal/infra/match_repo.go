@@ -0,0 +1,162 @@
+package infra
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/pkg/database"
+)
+
+type GameModel struct {
+	bun.BaseModel `bun:"table:games,alias:g"`
+
+	ID           string    `bun:",pk"` // MatchID
+	RoomID       string    `bun:"room_id"`
+	RoomName     string    `bun:"room_name"`
+	WinCondition string    `bun:"win_condition"`
+	StartedAt    time.Time `bun:"started_at"`
+	FinishedAt   time.Time `bun:"finished_at"`
+
+	Results []*GameResultModel `bun:"rel:has-many,join:id=game_id"`
+}
+
+type GameResultModel struct {
+	bun.BaseModel `bun:"table:game_results,alias:gr"`
+
+	GameID         string `bun:"game_id,pk"`
+	UserID         string `bun:"user_id,pk"`
+	DisplayName    string `bun:"display_name"`
+	IsCPU          bool   `bun:"is_cpu"`
+	Rank           int    `bun:"rank"`
+	Team           int    `bun:"team"`
+	TeamRank       int    `bun:"team_rank"`
+	DeadAt         int    `bun:"dead_at"`
+	Completed      int    `bun:"completed"`
+	DamageDealt    int    `bun:"damage_dealt"`
+	DamageReceived int    `bun:"damage_received"`
+}
+
+type matchRepository struct {
+	db *database.DB
+}
+
+func NewMatchRepository(db *database.DB) repository.MatchRepository {
+	return &matchRepository{
+		db: db,
+	}
+}
+
+func convertToDomainMatch(game *GameModel) *model.MatchRecord {
+	results := make([]*model.MatchResult, 0, len(game.Results))
+	for _, r := range game.Results {
+		results = append(results, &model.MatchResult{
+			UserID:         r.UserID,
+			DisplayName:    r.DisplayName,
+			IsCPU:          r.IsCPU,
+			Rank:           r.Rank,
+			Team:           r.Team,
+			TeamRank:       r.TeamRank,
+			DeadAt:         r.DeadAt,
+			Completed:      r.Completed,
+			DamageDealt:    r.DamageDealt,
+			DamageReceived: r.DamageReceived,
+		})
+	}
+
+	return &model.MatchRecord{
+		ID:           game.ID,
+		RoomID:       game.RoomID,
+		RoomName:     game.RoomName,
+		WinCondition: game.WinCondition,
+		StartedAt:    game.StartedAt,
+		FinishedAt:   game.FinishedAt,
+		Results:      results,
+	}
+}
+
+// SaveMatch implements repository.MatchRepository.
+func (r *matchRepository) SaveMatch(ctx context.Context, match *model.MatchRecord) error {
+	game := &GameModel{
+		ID:           match.ID,
+		RoomID:       match.RoomID,
+		RoomName:     match.RoomName,
+		WinCondition: match.WinCondition,
+		StartedAt:    match.StartedAt,
+		FinishedAt:   match.FinishedAt,
+	}
+	results := make([]*GameResultModel, 0, len(match.Results))
+	for _, res := range match.Results {
+		results = append(results, &GameResultModel{
+			GameID:         match.ID,
+			UserID:         res.UserID,
+			DisplayName:    res.DisplayName,
+			IsCPU:          res.IsCPU,
+			Rank:           res.Rank,
+			Team:           res.Team,
+			TeamRank:       res.TeamRank,
+			DeadAt:         res.DeadAt,
+			Completed:      res.Completed,
+			DamageDealt:    res.DamageDealt,
+			DamageReceived: res.DamageReceived,
+		})
+	}
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(game).Exec(ctx); err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+		_, err := tx.NewInsert().Model(&results).Exec(ctx)
+		return err
+	})
+	return errors.WithStack(err)
+}
+
+// GetUserMatches implements repository.MatchRepository.
+func (r *matchRepository) GetUserMatches(ctx context.Context, userID string, limit, offset int) ([]*model.MatchRecord, error) {
+	var games []*GameModel
+	err := r.db.NewSelect().
+		Model(&games).
+		Where("EXISTS (SELECT 1 FROM game_results WHERE game_results.game_id = g.id AND game_results.user_id = ?)", userID).
+		Relation("Results", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("gr.user_id = ?", userID)
+		}).
+		OrderExpr("g.finished_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	matches := make([]*model.MatchRecord, 0, len(games))
+	for _, game := range games {
+		matches = append(matches, convertToDomainMatch(game))
+	}
+
+	return matches, nil
+}
+
+// GetMatch implements repository.MatchRepository.
+func (r *matchRepository) GetMatch(ctx context.Context, id string) (*model.MatchRecord, error) {
+	var game GameModel
+	err := r.db.NewSelect().
+		Model(&game).
+		Where("g.id = ?", id).
+		Relation("Results", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.OrderExpr("gr.rank ASC")
+		}).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return convertToDomainMatch(&game), nil
+}
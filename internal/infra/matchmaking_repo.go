@@ -0,0 +1,211 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+)
+
+const (
+	// 待ち行列 (userID -> ticket)
+	RedisMatchmakingQueueKey string = "matchmaking:queue"
+	RedisMatchmakingLockKey  string = "matchmaking:lock"
+	// ルームごとの確保済みの席 (userID -> 期限 unix milli)
+	RedisMatchmakingReservedKey string = "matchmaking:reserved:"
+	// ユーザーごとのマッチングの結果
+	RedisMatchmakingResultKey string = "matchmaking:result:"
+)
+
+var touchTicketScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+type matchmakingRepository struct {
+	redis *redis.Client
+}
+
+func NewMatchmakingRepository(redis *redis.Client) repository.MatchmakingRepository {
+	return &matchmakingRepository{
+		redis: redis,
+	}
+}
+
+// Enqueue implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) Enqueue(ctx context.Context, ticket *model.MatchTicket) error {
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		// 前回の結果が残っていると古いルームに案内してしまう
+		pipe.Del(ctx, RedisMatchmakingResultKey+ticket.UserID)
+		pipe.HSet(ctx, RedisMatchmakingQueueKey, ticket.UserID, data)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// Dequeue implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) Dequeue(ctx context.Context, userID string) (bool, error) {
+	n, err := r.redis.HDel(ctx, RedisMatchmakingQueueKey, userID).Result()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
+
+// GetTicket implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) GetTicket(ctx context.Context, userID string) (*model.MatchTicket, error) {
+	data, err := r.redis.HGet(ctx, RedisMatchmakingQueueKey, userID).Bytes()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var ticket model.MatchTicket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &ticket, nil
+}
+
+// GetTickets implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) GetTickets(ctx context.Context) ([]*model.MatchTicket, error) {
+	res, err := r.redis.HGetAll(ctx, RedisMatchmakingQueueKey).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	tickets := make([]*model.MatchTicket, 0, len(res))
+	for _, data := range res {
+		var ticket model.MatchTicket
+		if err := json.Unmarshal([]byte(data), &ticket); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		tickets = append(tickets, &ticket)
+	}
+
+	return tickets, nil
+}
+
+// Touch implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) Touch(ctx context.Context, userID string, now time.Time) (bool, error) {
+	ticket, err := r.GetTicket(ctx, userID)
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ticket.PolledAt = now.UnixMilli()
+
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	// マッチングで外された直後に戻さないように、並んでいる場合だけ更新する
+	n, err := touchTicketScript.Run(ctx, r.redis, []string{RedisMatchmakingQueueKey}, userID, data).Int()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
+
+// Lock implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) Lock(ctx context.Context, ttl time.Duration) (bool, error) {
+	ok, err := r.redis.SetNX(ctx, RedisMatchmakingLockKey, 1, ttl).Result()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return ok, nil
+}
+
+// Reserve implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) Reserve(ctx context.Context, roomID, userID string, ttl time.Duration) error {
+	key := RedisMatchmakingReservedKey + roomID
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().Add(ttl).UnixMilli()), Member: userID})
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// Release implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) Release(ctx context.Context, roomID, userID string) error {
+	return errors.WithStack(r.redis.ZRem(ctx, RedisMatchmakingReservedKey+roomID, userID).Err())
+}
+
+// GetReserved implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) GetReserved(ctx context.Context, roomID string) (int, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	n, err := r.redis.ZCount(ctx, RedisMatchmakingReservedKey+roomID, "("+now, "+inf").Result()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return int(n), nil
+}
+
+// IsReserved implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) IsReserved(ctx context.Context, roomID, userID string) (bool, error) {
+	expireAt, err := r.redis.ZScore(ctx, RedisMatchmakingReservedKey+roomID, userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return expireAt > float64(time.Now().UnixMilli()), nil
+}
+
+// PushResult implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) PushResult(ctx context.Context, userID string, result *model.MatchFound, ttl time.Duration) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	key := RedisMatchmakingResultKey + userID
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// WaitResult implements repository.MatchmakingRepository.
+func (r *matchmakingRepository) WaitResult(ctx context.Context, userID string, timeout time.Duration) (*model.MatchFound, error) {
+	res, err := r.redis.BLPop(ctx, timeout, RedisMatchmakingResultKey+userID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var result model.MatchFound
+	if err := json.Unmarshal([]byte(res[1]), &result); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &result, nil
+}
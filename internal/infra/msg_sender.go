@@ -16,8 +16,10 @@ const writeWait = 10 * time.Millisecond
 type Client struct {
 	conn   *websocket.Conn
 	cancel chan struct{}
-	ch     chan interface{}
-	err    chan error
+	// close されると送信待ちのメッセージを送ってから接続を閉じる
+	done chan struct{}
+	ch   chan interface{}
+	err  chan error
 }
 
 func (c *Client) run() {
@@ -25,6 +27,11 @@ func (c *Client) run() {
 		select {
 		case <-c.cancel:
 			return
+		case <-c.done:
+			c.flush()
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			c.conn.Close()
+			return
 		case msg := <-c.ch:
 			err := c.conn.WriteJSON(msg)
 			if err != nil {
@@ -35,6 +42,19 @@ func (c *Client) run() {
 	}
 }
 
+func (c *Client) flush() {
+	for {
+		select {
+		case msg := <-c.ch:
+			if err := c.conn.WriteJSON(msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
 type MsgSender struct {
 	mutex   *sync.RWMutex
 	clients map[string]*Client
@@ -88,9 +108,16 @@ func (s *MsgSender) Register(userID string, conn *websocket.Conn, err chan error
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	// 再接続で同じユーザーが登録された場合は古い接続を閉じる
+	if old, ok := s.clients[userID]; ok {
+		close(old.cancel)
+		old.conn.Close()
+	}
+
 	client := &Client{
 		conn:   conn,
 		cancel: make(chan struct{}),
+		done:   make(chan struct{}),
 		ch:     make(chan interface{}, 100),
 		err:    err,
 	}
@@ -99,6 +126,7 @@ func (s *MsgSender) Register(userID string, conn *websocket.Conn, err chan error
 	s.clients[userID] = client
 }
 
+// Unregister は登録を解除し、送信待ちのメッセージを送ってから接続を閉じる
 func (s *MsgSender) Unregister(userID string) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -108,6 +136,20 @@ func (s *MsgSender) Unregister(userID string) {
 		return
 	}
 
+	close(client.done)
+	delete(s.clients, userID)
+}
+
+// UnregisterConn は conn がまだ登録されている場合だけ登録を解除する
+func (s *MsgSender) UnregisterConn(userID string, conn *websocket.Conn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	client, ok := s.clients[userID]
+	if !ok || client.conn != conn {
+		return
+	}
+
 	close(client.cancel)
 	delete(s.clients, userID)
 }
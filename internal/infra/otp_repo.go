@@ -9,6 +9,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// ルーム用の OTP が使えるルーム
+const RedisOTPRoomKey string = "otp:room:"
+
 type OTPRepository struct {
 	redis *redis.Client
 }
@@ -32,13 +35,43 @@ func (r *OTPRepository) GenerateOTP(ctx context.Context, userID, name string) (*
 	return otp, nil
 }
 
+func (r *OTPRepository) GenerateRoomOTP(ctx context.Context, roomID, userID, name string) (*model.OTP, error) {
+	otp, err := model.NewOTP()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, otp.OTP, userID+";"+name, 0)
+		pipe.Set(ctx, RedisOTPRoomKey+otp.OTP, roomID, 0)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return otp, nil
+}
+
+func (r *OTPRepository) GetOTPRoom(ctx context.Context, otp string) (string, error) {
+	roomID, err := r.redis.Get(ctx, RedisOTPRoomKey+otp).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return roomID, nil
+}
+
 func (r *OTPRepository) VerifyOTP(ctx context.Context, otp string) (string, error) {
 	res, err := r.redis.Get(ctx, otp).Result()
 	if err != nil {
 		return "", errors.WithStack(err)
 	}
 
-	if err := r.redis.Del(ctx, otp).Err(); err != nil {
+	if err := r.redis.Del(ctx, otp, RedisOTPRoomKey+otp).Err(); err != nil {
 		return "", errors.WithStack(err)
 	}
 
@@ -0,0 +1,189 @@
+package infra
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/pkg/database"
+)
+
+type RatingModel struct {
+	bun.BaseModel `bun:"table:ratings"`
+
+	UserID      string    `bun:"user_id,pk"`
+	Rating      float64   `bun:"rating"`
+	RD          float64   `bun:"rd"`
+	Volatility  float64   `bun:"volatility"`
+	GamesPlayed int       `bun:"games_played"`
+	UpdatedAt   time.Time `bun:"updated_at"`
+}
+
+type RatingHistoryModel struct {
+	bun.BaseModel `bun:"table:rating_history"`
+
+	ID           int64     `bun:",pk,autoincrement"`
+	UserID       string    `bun:"user_id"`
+	GameID       string    `bun:"game_id"`
+	RatingBefore float64   `bun:"rating_before"`
+	RatingAfter  float64   `bun:"rating_after"`
+	RD           float64   `bun:"rd"`
+	CreatedAt    time.Time `bun:"created_at"`
+}
+
+type ratingRepository struct {
+	db *database.DB
+}
+
+func NewRatingRepository(db *database.DB) repository.RatingRepository {
+	return &ratingRepository{
+		db: db,
+	}
+}
+
+func convertToDomainRating(rating *RatingModel) *model.Rating {
+	return &model.Rating{
+		UserID:      rating.UserID,
+		Rating:      rating.Rating,
+		RD:          rating.RD,
+		Volatility:  rating.Volatility,
+		GamesPlayed: rating.GamesPlayed,
+		UpdatedAt:   rating.UpdatedAt,
+	}
+}
+
+func convertToDBRating(rating *model.Rating) *RatingModel {
+	return &RatingModel{
+		UserID:      rating.UserID,
+		Rating:      rating.Rating,
+		RD:          rating.RD,
+		Volatility:  rating.Volatility,
+		GamesPlayed: rating.GamesPlayed,
+		UpdatedAt:   rating.UpdatedAt,
+	}
+}
+
+// GetRatings implements repository.RatingRepository.
+func (r *ratingRepository) GetRatings(ctx context.Context, userIDs []string) (map[string]*model.Rating, error) {
+	res := make(map[string]*model.Rating, len(userIDs))
+	for _, id := range userIDs {
+		res[id] = model.NewRating(id)
+	}
+	if len(userIDs) == 0 {
+		return res, nil
+	}
+
+	var ratings []*RatingModel
+	if err := r.db.NewSelect().Model(&ratings).Where("user_id IN (?)", bun.In(userIDs)).Scan(ctx); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, rating := range ratings {
+		res[rating.UserID] = convertToDomainRating(rating)
+	}
+
+	return res, nil
+}
+
+// EditRatings implements repository.RatingRepository.
+// まだ行がないユーザーもロックできるように初期値の行を先に作り、デッドロックしないように ID の順にロックする
+func (r *ratingRepository) EditRatings(ctx context.Context, matchID string, userIDs []string, fn func(map[string]*model.Rating) []*model.RatingChange) ([]*model.RatingChange, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	ids := slices.Clone(userIDs)
+	slices.Sort(ids)
+
+	var changes []*model.RatingChange
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		initial := make([]*RatingModel, 0, len(ids))
+		for _, id := range ids {
+			rating := model.NewRating(id)
+			rating.UpdatedAt = time.Now()
+			initial = append(initial, convertToDBRating(rating))
+		}
+		if _, err := tx.NewInsert().Model(&initial).Ignore().Exec(ctx); err != nil {
+			return err
+		}
+
+		var current []*RatingModel
+		if err := tx.NewSelect().Model(&current).Where("user_id IN (?)", bun.In(ids)).Order("user_id").For("UPDATE").Scan(ctx); err != nil {
+			return err
+		}
+		ratings := make(map[string]*model.Rating, len(current))
+		for _, rating := range current {
+			ratings[rating.UserID] = convertToDomainRating(rating)
+		}
+
+		changes = fn(ratings)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		after := make([]*RatingModel, 0, len(changes))
+		history := make([]*RatingHistoryModel, 0, len(changes))
+		for _, c := range changes {
+			after = append(after, convertToDBRating(c.After))
+			history = append(history, &RatingHistoryModel{
+				UserID:       c.UserID,
+				GameID:       matchID,
+				RatingBefore: c.Before.Rating,
+				RatingAfter:  c.After.Rating,
+				RD:           c.After.RD,
+				CreatedAt:    c.After.UpdatedAt,
+			})
+		}
+
+		_, err := tx.NewInsert().
+			Model(&after).
+			On("DUPLICATE KEY UPDATE").
+			Set("rating = VALUES(rating)").
+			Set("rd = VALUES(rd)").
+			Set("volatility = VALUES(volatility)").
+			Set("games_played = VALUES(games_played)").
+			Set("updated_at = VALUES(updated_at)").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.NewInsert().Model(&history).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return changes, nil
+}
+
+// GetHistory implements repository.RatingRepository.
+func (r *ratingRepository) GetHistory(ctx context.Context, userID string, limit int) ([]*model.RatingHistory, error) {
+	var history []*RatingHistoryModel
+	err := r.db.NewSelect().
+		Model(&history).
+		Where("user_id = ?", userID).
+		OrderExpr("created_at DESC, id DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := make([]*model.RatingHistory, 0, len(history))
+	for _, h := range history {
+		res = append(res, &model.RatingHistory{
+			UserID:    h.UserID,
+			MatchID:   h.GameID,
+			Before:    h.RatingBefore,
+			After:     h.RatingAfter,
+			RD:        h.RD,
+			CreatedAt: h.CreatedAt,
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,141 @@
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+)
+
+const (
+	// 進行中の試合のメッセージ (roomID ごと)
+	RedisReplayRoomKey string = "replay:room:"
+	// 保存したリプレイの情報とメッセージ (MatchID ごと)
+	RedisReplayKey       string = "replay:"
+	RedisReplayEventsKey string = "replay:events:"
+	// ユーザーが参加したリプレイ (開始時刻順)
+	RedisReplayUserKey string = "replay:user:"
+)
+
+// 進行中の試合のメッセージは game と同じだけ保持する
+const replayRoomTTL = 30 * time.Minute
+
+type replayRepository struct {
+	redis *redis.Client
+}
+
+func NewReplayRepository(redis *redis.Client) repository.ReplayRepository {
+	return &replayRepository{
+		redis: redis,
+	}
+}
+
+// Record implements repository.ReplayRepository.
+func (r *replayRepository) Record(ctx context.Context, roomID string, event *model.ReplayEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	key := RedisReplayRoomKey + roomID
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		pipe.Expire(ctx, key, replayRoomTTL)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// Reset implements repository.ReplayRepository.
+func (r *replayRepository) Reset(ctx context.Context, roomID string) error {
+	return errors.WithStack(r.redis.Del(ctx, RedisReplayRoomKey+roomID).Err())
+}
+
+// Save implements repository.ReplayRepository.
+func (r *replayRepository) Save(ctx context.Context, replay *model.Replay, ttl time.Duration) error {
+	data, err := json.Marshal(replay)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	eventsKey := RedisReplayEventsKey + replay.ID
+	if err := r.redis.Rename(ctx, RedisReplayRoomKey+replay.RoomID, eventsKey).Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Expire(ctx, eventsKey, ttl)
+		pipe.Set(ctx, RedisReplayKey+replay.ID, data, ttl)
+		for _, userID := range replay.UserIDs {
+			key := RedisReplayUserKey + userID
+			pipe.ZAdd(ctx, key, redis.Z{Score: float64(replay.StartAt), Member: replay.ID})
+			// 期限切れのリプレイを一覧から外す
+			pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(time.Now().Add(-ttl).UnixMilli(), 10))
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// GetReplay implements repository.ReplayRepository.
+func (r *replayRepository) GetReplay(ctx context.Context, id string) (*model.Replay, error) {
+	data, err := r.redis.Get(ctx, RedisReplayKey+id).Bytes()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var replay model.Replay
+	if err := json.Unmarshal(data, &replay); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &replay, nil
+}
+
+// GetEvents implements repository.ReplayRepository.
+func (r *replayRepository) GetEvents(ctx context.Context, id string) ([]*model.ReplayEvent, error) {
+	res, err := r.redis.LRange(ctx, RedisReplayEventsKey+id, 0, -1).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	events := make([]*model.ReplayEvent, 0, len(res))
+	for _, data := range res {
+		var event model.ReplayEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+// GetUserReplays implements repository.ReplayRepository.
+func (r *replayRepository) GetUserReplays(ctx context.Context, userID string, limit int) ([]*model.Replay, error) {
+	ids, err := r.redis.ZRevRange(ctx, RedisReplayUserKey+userID, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	replays := make([]*model.Replay, 0, len(ids))
+	for _, id := range ids {
+		replay, err := r.GetReplay(ctx, id)
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		replays = append(replays, replay)
+	}
+
+	return replays, nil
+}
@@ -2,7 +2,8 @@ package infra
 
 import (
 	"context"
-	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -23,8 +24,24 @@ type RoomModel struct {
 	MaxUserNum int    `bun:"max_user_num"`
 	UseCPU     bool   `bun:"use_cpu"`
 	Status     string `bun:"status"`
+	Duration   int    `bun:"duration"`
+	TeamNum    int    `bun:"team_num"`
+
+	WinCondition string `bun:"win_condition"`
+	WinTarget    int    `bun:"win_target"`
+
+	AutoStart bool `bun:"auto_start"`
+
+	Private      bool   `bun:"private"`
+	InviteCode   string `bun:"invite_code"`
+	PasswordHash string `bun:"password_hash"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
 }
 
+// LIKE のワイルドカードを文字として扱う
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type roomRepository struct {
 	db *database.DB
 }
@@ -45,6 +62,19 @@ func convertToDomainModel(room *RoomModel) *model.Room {
 		MaxUserNum: room.MaxUserNum,
 		UseCPU:     room.UseCPU,
 		Status:     room.Status,
+		Duration:   room.Duration,
+		TeamNum:    room.TeamNum,
+
+		WinCondition: room.WinCondition,
+		WinTarget:    room.WinTarget,
+
+		AutoStart: room.AutoStart,
+
+		Private:      room.Private,
+		InviteCode:   room.InviteCode,
+		PasswordHash: room.PasswordHash,
+
+		CreatedAt: room.CreatedAt,
 	}
 }
 
@@ -58,13 +88,54 @@ func convertToDBModel(room *model.Room) *RoomModel {
 		MaxUserNum: room.MaxUserNum,
 		UseCPU:     room.UseCPU,
 		Status:     room.Status,
+		Duration:   room.Duration,
+		TeamNum:    room.TeamNum,
+
+		WinCondition: room.WinCondition,
+		WinTarget:    room.WinTarget,
+
+		AutoStart: room.AutoStart,
+
+		Private:      room.Private,
+		InviteCode:   room.InviteCode,
+		PasswordHash: room.PasswordHash,
+
+		CreatedAt: room.CreatedAt,
 	}
 }
 
-func (r *roomRepository) GetRooms(ctx context.Context) ([]*model.Room, error) {
+func (r *roomRepository) GetRooms(ctx context.Context, filter *model.RoomFilter, after *model.RoomCursor, limit int) ([]*model.Room, int, error) {
 	var roomModels []*RoomModel
-	if err := r.db.NewSelect().Model(&roomModels).Scan(ctx); err != nil {
-		return nil, errors.WithStack(err)
+	query := r.db.NewSelect().Model(&roomModels).Where("private = ?", false)
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN (?)", bun.In(filter.Statuses))
+	} else {
+		query = query.Where("status != ?", model.RoomStatusFinish)
+	}
+	if filter.UseCPU != nil {
+		query = query.Where("use_cpu = ?", *filter.UseCPU)
+	}
+	if filter.Name != "" {
+		query = query.Where("name LIKE ?", "%"+likeEscaper.Replace(filter.Name)+"%")
+	}
+
+	total, err := query.Count(ctx)
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+
+	if after != nil {
+		query = query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("created_at < ?", after.CreatedAt).
+				WhereOr("created_at = ? AND id < ?", after.CreatedAt, after.ID)
+		})
+	}
+	query = query.Order("created_at DESC", "id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Scan(ctx); err != nil {
+		return nil, 0, errors.WithStack(err)
 	}
 
 	rooms := make([]*model.Room, 0, len(roomModels))
@@ -72,7 +143,7 @@ func (r *roomRepository) GetRooms(ctx context.Context) ([]*model.Room, error) {
 		rooms = append(rooms, convertToDomainModel(roomModel))
 	}
 
-	return rooms, nil
+	return rooms, total, nil
 }
 
 func (r *roomRepository) CreateRoom(ctx context.Context, room *model.Room) (string, error) {
@@ -84,20 +155,24 @@ func (r *roomRepository) CreateRoom(ctx context.Context, room *model.Room) (stri
 	return roomModel.ID, nil
 }
 
-func (r *roomRepository) Matching(ctx context.Context) (string, error) {
-	var randomRoom RoomModel
-	query := r.db.NewSelect().Model(&randomRoom).Where("status = ?", "pending").OrderExpr("RAND()").Limit(1)
-	err := query.Scan(ctx)
-
-	if errors.Is(err, sql.ErrNoRows) {
-		return "", nil
+func (r *roomRepository) GetPendingRooms(ctx context.Context) ([]*model.Room, error) {
+	var roomModels []*RoomModel
+	// パスワードはマッチングでは入力できないので、パスワード付きのルームも除く
+	err := r.db.NewSelect().Model(&roomModels).
+		Where("status = ?", model.RoomStatusPending).
+		Where("private = ?", false).
+		Where("password_hash = ''").
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
 
-	if err != nil {
-		return "", errors.WithStack(err)
+	rooms := make([]*model.Room, 0, len(roomModels))
+	for _, roomModel := range roomModels {
+		rooms = append(rooms, convertToDomainModel(roomModel))
 	}
 
-	return randomRoom.ID, nil
+	return rooms, nil
 }
 
 func (r *roomRepository) GetRoomByID(ctx context.Context, roomID string) (*model.Room, error) {
@@ -110,8 +185,40 @@ func (r *roomRepository) GetRoomByID(ctx context.Context, roomID string) (*model
 	return convertToDomainModel(&roomModel), nil
 }
 
+func (r *roomRepository) GetActiveRooms(ctx context.Context, createdBefore time.Time) ([]*model.Room, error) {
+	var roomModels []*RoomModel
+	err := r.db.NewSelect().Model(&roomModels).
+		Where("status IN (?)", bun.In([]string{model.RoomStatusPending, model.RoomStatusPlaying})).
+		Where("created_at < ?", createdBefore).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rooms := make([]*model.Room, 0, len(roomModels))
+	for _, roomModel := range roomModels {
+		rooms = append(rooms, convertToDomainModel(roomModel))
+	}
+
+	return rooms, nil
+}
+
+func (r *roomRepository) GetRoomByInviteCode(ctx context.Context, code string) (*model.Room, error) {
+	var roomModel RoomModel
+	err := r.db.NewSelect().Model(&roomModel).
+		Where("invite_code = ?", code).
+		Where("status != ?", model.RoomStatusFinish).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return convertToDomainModel(&roomModel), nil
+}
+
 func (r *roomRepository) UpdateRoom(ctx context.Context, room *model.Room) error {
 	roomModel := convertToDBModel(room)
-	_, err := r.db.NewUpdate().Model(roomModel).WherePK().Exec(ctx)
+	_, err := r.db.NewUpdate().Model(roomModel).OmitZero().WherePK().Exec(ctx)
 	return errors.WithStack(err)
 }
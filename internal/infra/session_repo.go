@@ -0,0 +1,63 @@
+package infra
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/pkg/otp"
+)
+
+const RedisReconnectKey string = "reconnect:"
+
+type sessionRepository struct {
+	redis *redis.Client
+}
+
+func NewSessionRepository(redis *redis.Client) repository.SessionRepository {
+	return &sessionRepository{
+		redis: redis,
+	}
+}
+
+// IssueReconnectToken implements repository.SessionRepository.
+func (r *sessionRepository) IssueReconnectToken(ctx context.Context, roomID, userID string, ttl time.Duration) (string, error) {
+	token, err := otp.GenerateOTP(32)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if err := r.redis.Set(ctx, RedisReconnectKey+token, roomID+";"+userID, ttl).Err(); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return token, nil
+}
+
+// VerifyReconnectToken implements repository.SessionRepository.
+func (r *sessionRepository) VerifyReconnectToken(ctx context.Context, token string) (string, string, error) {
+	res, err := r.redis.Get(ctx, RedisReconnectKey+token).Result()
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	s := strings.SplitN(res, ";", 2)
+	if len(s) != 2 {
+		return "", "", errors.New("invalid reconnect token")
+	}
+
+	return s[0], s[1], nil
+}
+
+// RevokeReconnectToken implements repository.SessionRepository.
+func (r *sessionRepository) RevokeReconnectToken(ctx context.Context, token string) error {
+	if err := r.redis.Del(ctx, RedisReconnectKey+token).Err(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package infra
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"github.com/uptrace/bun"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/pkg/database"
+)
+
+type UserStatsModel struct {
+	bun.BaseModel `bun:"table:user_stats"`
+
+	UserID       string      `bun:"user_id,pk"`
+	GamesPlayed  int         `bun:"games_played"`
+	Wins         int         `bun:"wins"`
+	TypedChars   int         `bun:"typed_chars"`
+	Mistypes     int         `bun:"mistypes"`
+	TypingMillis int64       `bun:"typing_millis"`
+	BestWPM      float64     `bun:"best_wpm"`
+	LevelCounts  map[int]int `bun:"level_counts,type:json"`
+}
+
+type statsRepository struct {
+	db *database.DB
+}
+
+func NewStatsRepository(db *database.DB) repository.StatsRepository {
+	return &statsRepository{
+		db: db,
+	}
+}
+
+func convertToDomainStats(stats *UserStatsModel) *model.UserStats {
+	return &model.UserStats{
+		UserID:       stats.UserID,
+		GamesPlayed:  stats.GamesPlayed,
+		Wins:         stats.Wins,
+		TypedChars:   stats.TypedChars,
+		Mistypes:     stats.Mistypes,
+		TypingMillis: stats.TypingMillis,
+		BestWPM:      stats.BestWPM,
+		LevelCounts:  stats.LevelCounts,
+	}
+}
+
+func convertToDBStats(stats *model.UserStats) *UserStatsModel {
+	return &UserStatsModel{
+		UserID:       stats.UserID,
+		GamesPlayed:  stats.GamesPlayed,
+		Wins:         stats.Wins,
+		TypedChars:   stats.TypedChars,
+		Mistypes:     stats.Mistypes,
+		TypingMillis: stats.TypingMillis,
+		BestWPM:      stats.BestWPM,
+		LevelCounts:  stats.LevelCounts,
+	}
+}
+
+// AddGameStats implements repository.StatsRepository.
+// 行がまだないユーザーが同時に終わっても重ならないように、INSERT ... ON DUPLICATE KEY UPDATE で足す
+// レベルごとの数は JSON なので、行をロックしたまま読み込んで足し直す
+func (r *statsRepository) AddGameStats(ctx context.Context, stats []*model.UserStats) error {
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, game := range stats {
+			res, err := tx.NewInsert().
+				Model(convertToDBStats(game)).
+				On("DUPLICATE KEY UPDATE").
+				Set("games_played = games_played + VALUES(games_played)").
+				Set("wins = wins + VALUES(wins)").
+				Set("typed_chars = typed_chars + VALUES(typed_chars)").
+				Set("mistypes = mistypes + VALUES(mistypes)").
+				Set("typing_millis = typing_millis + VALUES(typing_millis)").
+				Set("best_wpm = GREATEST(best_wpm, VALUES(best_wpm))").
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+			// 新しく追加した行 (1) はそのまま、既存の行を更新した場合 (2) はレベルごとの数を足す
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n != 2 || len(game.LevelCounts) == 0 {
+				continue
+			}
+
+			var current UserStatsModel
+			if err := tx.NewSelect().Model(&current).Column("user_id", "level_counts").Where("user_id = ?", game.UserID).Scan(ctx); err != nil {
+				return err
+			}
+			total := &model.UserStats{LevelCounts: current.LevelCounts}
+			total.Add(&model.UserStats{LevelCounts: game.LevelCounts})
+			current.LevelCounts = total.LevelCounts
+			if _, err := tx.NewUpdate().Model(&current).Column("level_counts").WherePK().Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// GetUserStats implements repository.StatsRepository.
+func (r *statsRepository) GetUserStats(ctx context.Context, userID string) (*model.UserStats, error) {
+	var stats UserStatsModel
+	err := r.db.NewSelect().Model(&stats).Where("user_id = ?", userID).Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.UserStats{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return convertToDomainStats(&stats), nil
+}
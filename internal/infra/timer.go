@@ -0,0 +1,62 @@
+package infra
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/service"
+	"github.com/Simo-C3/stego2-server/pkg/config"
+)
+
+type timer struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewTimer(cfg *config.TimerConfig) service.Timer {
+	return &timer{
+		client:  http.DefaultClient,
+		baseURL: cfg.URL,
+	}
+}
+
+// Start implements service.Timer.
+func (t *timer) Start(ctx context.Context, gameID string, duration, delay int) error {
+	q := url.Values{}
+	q.Set("game", gameID)
+	q.Set("duration", strconv.Itoa(duration))
+	q.Set("delay", strconv.Itoa(delay))
+	return t.request(ctx, "/start", q)
+}
+
+// Stop implements service.Timer.
+func (t *timer) Stop(ctx context.Context, gameID string) error {
+	q := url.Values{}
+	q.Set("game", gameID)
+	return t.request(ctx, "/end", q)
+}
+
+func (t *timer) request(ctx context.Context, path string, q url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.baseURL+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return errors.Errorf("timer: %s %d: %s", path, res.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/handler"
+	myMiddleware "github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+func InitGameRouter(g *echo.Group, gameHandler *handler.GameHandler, am myMiddleware.AuthController) {
+	game := g.Group("/games")
+	game.GET("", gameHandler.GetMyGames, am.WithHeader)
+	game.GET("/:id", gameHandler.GetGame, am.WithHeader)
+}
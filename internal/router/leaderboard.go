@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/handler"
+	myMiddleware "github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+func InitLeaderboardRouter(g *echo.Group, leaderboardHandler *handler.LeaderboardHandler, am myMiddleware.AuthController) {
+	leaderboard := g.Group("/leaderboards")
+	leaderboard.GET("/:kind", leaderboardHandler.GetLeaderboard, am.WithHeader)
+	leaderboard.GET("/:kind/me", leaderboardHandler.GetMyEntry, am.WithHeader)
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/handler"
+	myMiddleware "github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+func InitMatchingRouter(g *echo.Group, matchingHandler *handler.MatchingHandler, am myMiddleware.AuthController) {
+	matching := g.Group("/rooms/matching")
+	matching.POST("", matchingHandler.Enqueue, am.WithHeader)
+	matching.GET("", matchingHandler.Matching, am.WithHeader)
+	matching.GET("/result", matchingHandler.Wait, am.WithHeader)
+	matching.DELETE("", matchingHandler.Cancel, am.WithHeader)
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/handler"
+	myMiddleware "github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+func InitReplayRouter(g *echo.Group, replayHandler *handler.ReplayHandler, am myMiddleware.AuthController) {
+	g.GET("/users/:id/replays", replayHandler.GetUserReplays, am.WithHeader)
+
+	replay := g.Group("/replays")
+	replay.GET("/:id", replayHandler.GetReplay, am.WithHeader)
+	replay.GET("/:id/stream", replayHandler.StreamReplay)
+}
@@ -11,6 +11,5 @@ func InitRoomRouter(g *echo.Group, roomHandler *handler.RoomHandler, am myMiddle
 	room := g.Group("/rooms")
 	room.GET("", roomHandler.GetRooms, am.WithHeader)
 	room.POST("", roomHandler.CreateRoom, am.WithHeader)
-	room.GET("/matching", roomHandler.Matching, am.WithHeader)
 	room.GET("/:id", roomHandler.JoinRoom)
 }
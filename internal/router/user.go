@@ -0,0 +1,14 @@
+package router
+
+import (
+	"github.com/labstack/echo/v4"
+
+	"github.com/Simo-C3/stego2-server/internal/handler"
+	myMiddleware "github.com/Simo-C3/stego2-server/pkg/middleware"
+)
+
+func InitUserRouter(g *echo.Group, userHandler *handler.UserHandler, am myMiddleware.AuthController) {
+	user := g.Group("/users")
+	user.GET("/:id/stats", userHandler.GetUserStats, am.WithHeader)
+	user.GET("/:id/rating", userHandler.GetUserRating, am.WithHeader)
+}
@@ -13,6 +13,31 @@ const (
 	TypeStartGame             Type = "StartGame"
 	TypeChangeWordDifficult   Type = "ChangeWordDifficult"
 	TypeResult                Type = "Result"
+	TypeStreak                Type = "Streak"
+	TypeChangeTarget          Type = "ChangeTarget"
+	TypeShield                Type = "Shield"
+	TypeReflect               Type = "Reflect"
+	TypeFreeze                Type = "Freeze"
+	TypeDoubleDamage          Type = "DoubleDamage"
+	TypeRemainingTime         Type = "RemainingTime"
+	TypeAssignTeam            Type = "AssignTeam"
+	TypeChangeTeam            Type = "ChangeTeam"
+	TypeSnapshot              Type = "Snapshot"
+	TypeReconnectToken        Type = "ReconnectToken"
+	TypeReady                 Type = "Ready"
+	TypeCancelCountdown       Type = "CancelCountdown"
+	TypeCountdown             Type = "Countdown"
+	TypeLobby                 Type = "Lobby"
+	TypeTransferOwner         Type = "TransferOwner"
+	TypeKick                  Type = "Kick"
+	TypeBan                   Type = "Ban"
+	TypeKicked                Type = "Kicked"
+	TypeLeave                 Type = "Leave"
+	TypeRematch               Type = "Rematch"
+	TypeRematchVote           Type = "RematchVote"
+	TypeReplayEvent           Type = "ReplayEvent"
+	TypeReplayEnd             Type = "ReplayEnd"
+	TypeRejectInput           Type = "RejectInput"
 )
 
 type Base struct {
@@ -26,6 +51,21 @@ type AttackEvent struct {
 	Damage int    `json:"damage"`
 }
 
+const (
+	EffectStateActivated = "activated"
+	EffectStateTriggered = "triggered"
+)
+
+// EffectEvent は特殊シーケンスの効果の発動を通知する
+type EffectEvent struct {
+	UserID   string `json:"userId"`
+	State    string `json:"state"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Damage   int    `json:"damage,omitempty"`
+	Duration int    `json:"duration,omitempty"` // ms
+}
+
 type NextSeqEvent struct {
 	Value string `json:"value"`
 	Type  string `json:"type"`
@@ -46,6 +86,92 @@ type TypingKey struct {
 	} `json:"payload"`
 }
 
+type ChangeTarget struct {
+	Type    Type `json:"type"`
+	Payload struct {
+		Mode   string `json:"mode"`
+		UserID string `json:"userId"`
+	} `json:"payload"`
+}
+
+type AssignTeam struct {
+	Type    Type `json:"type"`
+	Payload struct {
+		UserID string `json:"userId"`
+		Team   int    `json:"team"`
+	} `json:"payload"`
+}
+
+type TransferOwner struct {
+	Type    Type `json:"type"`
+	Payload struct {
+		UserID string `json:"userId"`
+	} `json:"payload"`
+}
+
+// Moderate は Kick と Ban のメッセージ
+type Moderate struct {
+	Type    Type `json:"type"`
+	Payload struct {
+		UserID string `json:"userId"`
+	} `json:"payload"`
+}
+
+const (
+	KickReasonKick = "kick"
+	KickReasonBan  = "ban"
+	// 自分から抜けた、または切断の猶予が過ぎた
+	KickReasonLeave = "leave"
+	// 放置されたルームが閉じられた。UserID は空
+	KickReasonClosed = "closed"
+)
+
+type Kicked struct {
+	UserID string `json:"userId"`
+	Reason string `json:"reason"`
+}
+
+type RematchVote struct {
+	UserIDs  []string `json:"userIds"`
+	Required int      `json:"required"`
+}
+
+type ChangeTeam struct {
+	UserID string `json:"userId"`
+	Team   int    `json:"team"`
+}
+
+type Ready struct {
+	Type    Type `json:"type"`
+	Payload struct {
+		Ready bool `json:"ready"`
+	} `json:"payload"`
+}
+
+type Countdown struct {
+	Remaining int  `json:"remaining"` // sec
+	Cancelled bool `json:"cancelled"`
+}
+
+// Lobby はロビーの参加者の一覧
+type Lobby struct {
+	OwnerID    string       `json:"ownerId"`
+	MinUserNum int          `json:"minUserNum"`
+	MaxUserNum int          `json:"maxUserNum"`
+	AutoStart  bool         `json:"autoStart"`
+	Users      []*LobbyUser `json:"users"`
+}
+
+type LobbyUser struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Ready      bool    `json:"ready"`
+	Team       int     `json:"team"`
+	IsCPU      bool    `json:"isCpu"`
+	Connection string  `json:"connection"`
+	Rating     float64 `json:"rating,omitempty"`
+}
+
 type ChangeRoomState struct {
 	Type    Type                   `json:"type"`
 	Payload ChangeRoomStatePayload `json:"payload"`
@@ -66,24 +192,86 @@ type ChangeRoomStatePayload struct {
 }
 
 type ChangeOtherUserState struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Life     int    `json:"life"`
-	Seq      string `json:"seq"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Life       int    `json:"life"`
+	Seq        string `json:"seq"`
+	InputSeq   string `json:"inputSeq"`
+	Rank       int    `json:"rank"`
+	Streak     int    `json:"streak"`
+	Target     Target `json:"target"`
+	Team       int    `json:"team"`
+	Connection string `json:"connection"`
+}
+
+type ReconnectToken struct {
+	Token       string `json:"token"`
+	GracePeriod int    `json:"gracePeriod"` // sec
+}
+
+type Target struct {
+	Mode   string `json:"mode"`
+	UserID string `json:"userId,omitempty"`
+}
+
+const (
+	RejectReasonMistype = "mistype"
+	RejectReasonFrozen  = "frozen"
+)
+
+// RejectInput は受け付けなかった入力の通知。InputSeq はサーバーが受け付けている入力
+type RejectInput struct {
 	InputSeq string `json:"inputSeq"`
-	Rank     int    `json:"rank"`
+	Reason   string `json:"reason"`
+}
+
+type StreakEvent struct {
+	UserID     string `json:"userId"`
+	Streak     int    `json:"streak"`
+	Multiplier int    `json:"multiplier"`
+}
+
+type RemainingTime struct {
+	Remaining int `json:"remaining"` // sec
+}
+
+// Snapshot はルームの全員の状態
+type Snapshot struct {
+	Room  ChangeRoomStatePayload `json:"room"`
+	Users []*PlayerSnapshot      `json:"users"`
+}
+
+type PlayerSnapshot struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Life       int             `json:"life"`
+	Sequences  []*NextSeqEvent `json:"sequences"`
+	Pos        int             `json:"pos"`
+	Difficult  int             `json:"difficult"`
+	Streak     int             `json:"streak"`
+	Team       int             `json:"team"`
+	IsCPU      bool            `json:"isCpu"`
+	DeadAt     int             `json:"deadAt"`
+	Connection string          `json:"connection"`
 }
 
 type Result struct {
 	UserID      string `json:"userId"`
 	Rank        int    `json:"rank"`
 	DisplayName string `json:"displayName"`
+	Team        int    `json:"team,omitempty"`
+	TeamRank    int    `json:"teamRank,omitempty"`
+	// CPU 以外が2人以上の試合でのレーティングと変化量
+	Rating      float64 `json:"rating,omitempty"`
+	RatingDelta float64 `json:"ratingDelta,omitempty"`
 }
 
-func NewResult(userID string, rank int, displayName string) *Result {
+func NewResult(userID string, rank int, displayName string, team, teamRank int) *Result {
 	return &Result{
 		UserID:      userID,
 		Rank:        rank,
 		DisplayName: displayName,
+		Team:        team,
+		TeamRank:    teamRank,
 	}
 }
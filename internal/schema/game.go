@@ -0,0 +1,31 @@
+package schema
+
+type (
+	Game struct {
+		ID           string        `json:"id"`
+		RoomID       string        `json:"roomId"`
+		RoomName     string        `json:"roomName"`
+		WinCondition string        `json:"winCondition"`
+		StartedAt    int64         `json:"startedAt"`  // unix sec
+		FinishedAt   int64         `json:"finishedAt"` // unix sec
+		Results      []*GameResult `json:"results"`
+	}
+
+	GameResult struct {
+		UserID         string `json:"userId"`
+		DisplayName    string `json:"displayName"`
+		IsCPU          bool   `json:"isCpu"`
+		Rank           int    `json:"rank"`
+		Team           int    `json:"team,omitempty"`
+		TeamRank       int    `json:"teamRank,omitempty"`
+		DeadAt         int    `json:"deadAt"` // unix sec, 0 なら生存
+		Completed      int    `json:"completed"`
+		DamageDealt    int    `json:"damageDealt"`
+		DamageReceived int    `json:"damageReceived"`
+	}
+
+	GetGamesQuery struct {
+		Limit  int `query:"limit"`
+		Offset int `query:"offset"`
+	}
+)
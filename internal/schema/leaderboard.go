@@ -0,0 +1,22 @@
+package schema
+
+type (
+	LeaderboardEntry struct {
+		UserID      string  `json:"userId"`
+		DisplayName string  `json:"displayName"`
+		Score       float64 `json:"score"`
+		Rank        int     `json:"rank"`
+	}
+
+	GetLeaderboardQuery struct {
+		Kind   string `param:"kind"`
+		Period string `query:"period"`
+		Offset int    `query:"offset"`
+		Limit  int    `query:"limit"`
+	}
+
+	GetLeaderboardResponse struct {
+		Entries []*LeaderboardEntry `json:"entries"`
+		Total   int                 `json:"total"`
+	}
+)
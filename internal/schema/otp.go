@@ -1,5 +1,14 @@
 package schema
 
 type OTP struct {
-	OTP string `json:"otp"`
+	OTP    string `json:"otp"`
+	RoomID string `json:"roomId,omitempty"`
+}
+
+// GenerateOTPRequest は非公開・パスワード付きのルームに参加するときに指定する
+// どちらも指定しない場合はどのルームにも使える OTP を生成する
+type GenerateOTPRequest struct {
+	RoomID     string `json:"roomId"`
+	InviteCode string `json:"inviteCode"`
+	Password   string `json:"password"`
 }
@@ -5,4 +5,6 @@ type PublishContent struct {
 	Payload      any      `json:"payload"`
 	IncludeUsers []string `json:"includeUsers"`
 	ExcludeUsers []string `json:"excludeUsers"`
+	// 送信後に接続を閉じるユーザー。game の users にいなくても送る
+	CloseUsers []string `json:"closeUsers"`
 }
@@ -0,0 +1,38 @@
+package schema
+
+import "encoding/json"
+
+type (
+	Replay struct {
+		ID         string   `json:"id"`
+		RoomID     string   `json:"roomId"`
+		RoomName   string   `json:"roomName"`
+		UserIDs    []string `json:"userIds"`
+		StartedAt  int64    `json:"startedAt"`  // unix milli
+		FinishedAt int64    `json:"finishedAt"` // unix milli
+	}
+
+	ReplayEvent struct {
+		At           int64           `json:"at"` // 開始からの経過時間 (milli)
+		From         string          `json:"from,omitempty"`
+		IncludeUsers []string        `json:"includeUsers,omitempty"`
+		ExcludeUsers []string        `json:"excludeUsers,omitempty"`
+		Payload      json.RawMessage `json:"payload"`
+	}
+
+	GetReplayResponse struct {
+		Replay *Replay        `json:"replay"`
+		Events []*ReplayEvent `json:"events"`
+	}
+
+	GetReplaysQuery struct {
+		UserID string `param:"id"`
+		Limit  int    `query:"limit"`
+	}
+
+	StreamReplayQuery struct {
+		ID    string  `param:"id"`
+		Otp   string  `query:"p"`
+		Speed float64 `query:"speed"`
+	}
+)
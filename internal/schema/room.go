@@ -10,6 +10,19 @@ type (
 		MaxUserNum int    `json:"maxUserNum"`
 		UseCPU     bool   `json:"useCpu"`
 		Status     string `json:"status"`
+		Duration   int    `json:"duration"`
+		TeamNum    int    `json:"teamNum"`
+
+		WinCondition string `json:"winCondition"`
+		WinTarget    int    `json:"winTarget"`
+
+		AutoStart bool `json:"autoStart"`
+
+		Private     bool `json:"private"`
+		HasPassword bool `json:"hasPassword"`
+
+		UserNum   int   `json:"userNum"`   // 現在の参加人数
+		CreatedAt int64 `json:"createdAt"` // unix sec
 	}
 
 	CreateRoomRequest struct {
@@ -18,23 +31,48 @@ type (
 		MinUserNum int    `json:"minUserNum"`
 		MaxUserNum int    `json:"maxUserNum"`
 		UseCPU     bool   `json:"useCpu"`
+		Duration   int    `json:"duration"`
+		TeamNum    int    `json:"teamNum"`
+
+		WinCondition string `json:"winCondition"`
+		WinTarget    int    `json:"winTarget"`
+
+		AutoStart bool `json:"autoStart"`
+
+		Private  bool   `json:"private"`
+		Password string `json:"password"`
 	}
 
 	CreateRoomResponse struct {
-		RoomID string `json:"id"`
+		RoomID     string `json:"id"`
+		InviteCode string `json:"inviteCode,omitempty"`
+	}
+
+	GetRoomsQuery struct {
+		Status   string `query:"status"` // カンマ区切り
+		CPU      string `query:"cpu"`    // true / false
+		Name     string `query:"q"`
+		HasSpace bool   `query:"hasSpace"`
+		Sort     string `query:"sort"` // created / players
+		Cursor   string `query:"cursor"`
+		Limit    int    `query:"limit"`
 	}
 
 	GetRoomsResponse struct {
-		Rooms []*Room `json:"rooms"`
-		Total int     `json:"total"`
+		Rooms      []*Room `json:"rooms"`
+		Total      int     `json:"total"`
+		NextCursor string  `json:"nextCursor,omitempty"`
 	}
 
 	MatchingResponse struct {
-		ID string `json:"id"`
+		ID  string `json:"id"`
+		OTP string `json:"otp,omitempty"`
 	}
 
 	JoinRoomQuery struct {
-		ID  string `param:"id"`
-		Otp string `query:"p"`
+		ID        string `param:"id"`
+		Otp       string `query:"p"`
+		Spectate  bool   `query:"spectate"`
+		Reconnect string `query:"reconnect"`
 	}
 )
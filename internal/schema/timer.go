@@ -0,0 +1,8 @@
+package schema
+
+const TopicTimer = "game_notifications"
+
+type TimerNotification struct {
+	GameID    string `json:"gameId"`
+	Remaining int    `json:"remaining"`
+}
@@ -0,0 +1,31 @@
+package schema
+
+type (
+	UserStats struct {
+		UserID         string  `json:"userId"`
+		GamesPlayed    int     `json:"gamesPlayed"`
+		Wins           int     `json:"wins"`
+		AverageWPM     float64 `json:"averageWpm"`
+		BestWPM        float64 `json:"bestWpm"`
+		Accuracy       float64 `json:"accuracy"` // 0 から 1
+		TypedChars     int     `json:"typedChars"`
+		FavoriteLevels []int   `json:"favoriteLevels"`
+	}
+
+	Rating struct {
+		UserID      string           `json:"userId"`
+		Rating      float64          `json:"rating"`
+		RD          float64          `json:"rd"`
+		Volatility  float64          `json:"volatility"`
+		GamesPlayed int              `json:"gamesPlayed"`
+		History     []*RatingHistory `json:"history"`
+	}
+
+	RatingHistory struct {
+		GameID    string  `json:"gameId"`
+		Before    float64 `json:"before"`
+		After     float64 `json:"after"`
+		RD        float64 `json:"rd"`
+		CreatedAt int64   `json:"createdAt"` // unix sec
+	}
+)
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/pkg/logger"
+	"github.com/Simo-C3/stego2-server/pkg/uuid"
+)
+
+const cpuIDPrefix = "cpu-"
+
+// fillCPU は空いている席を MinUserNum まで CPU で埋める
+// 作成した CPU はユーザーとして保存されるが、game の users への追加は呼び出し側で行う
+func (gm *GameManager) fillCPU(ctx context.Context, game *model.Game) ([]*model.User, error) {
+	if !game.BaseRoom.UseCPU {
+		return nil, nil
+	}
+
+	num := game.BaseRoom.MinUserNum - len(game.Users)
+	if num <= 0 {
+		return nil, nil
+	}
+
+	cpus := make([]*model.User, 0, num)
+	for i := range num {
+		id, err := uuid.GenerateUUIDv7()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		cpu := model.NewCPUUser(cpuIDPrefix+id, fmt.Sprintf("CPU %d", i+1))
+		cpu.Sequences, err = gm.newSequences(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := gm.repo.UpdateUser(ctx, cpu); err != nil {
+			return nil, err
+		}
+		cpus = append(cpus, cpu)
+	}
+
+	return cpus, nil
+}
+
+// runCPU は CPU のタイピングを人間と同じ TypeKey 経由で進める
+// ゲームが終了するか CPU が死亡したら終了する
+func (gm *GameManager) runCPU(roomID, userID string) {
+	ctx := context.Background()
+	logger := logger.New()
+
+	// 1文字あたりの入力間隔 (1 word = 5 文字)
+	interval := time.Minute / time.Duration(max(1, gm.cpuCfg.WPM)*5)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	matchID := ""
+	// 失敗するかを決めたシーケンス
+	decided := ""
+	for range ticker.C {
+		// ゲームが消えたか終わった、または再戦で別の試合になったら止める
+		game, err := gm.repo.GetGameByID(ctx, roomID)
+		if err != nil || game.Status != model.GameStatusPlaying {
+			return
+		}
+		if matchID == "" {
+			matchID = game.MatchID
+		}
+		if game.MatchID != matchID {
+			return
+		}
+		user, ok := game.Users[userID]
+		if !ok || user.Life <= 0 {
+			return
+		}
+		if user.IsFrozen(time.Now()) {
+			continue
+		}
+
+		seq, err := user.CurrentSequence()
+		if err != nil {
+			return
+		}
+
+		// 打ち始める前に、難しいシーケンスほど高い確率で諦めて失敗する
+		if user.Pos == 0 && seq.Value != decided {
+			decided = seq.Value
+			if rand.Float64() < gm.cpuFailRate(seq.Level) {
+				if err := gm.failSeq(ctx, roomID, userID); err != nil {
+					logger.LogErrorWithStack(ctx, err)
+				}
+				continue
+			}
+		}
+
+		input := seq.Value[:user.Pos]
+		if rand.Float64() < gm.cpuCfg.ErrorRate {
+			// ミスタイプは TypeKey で弾かれる (エラーにはならない)
+			input += "#"
+		} else {
+			_, size := utf8.DecodeRuneInString(seq.Value[user.Pos:])
+			input = seq.Value[:user.Pos+size]
+		}
+
+		if err := gm.TypeKey(ctx, roomID, userID, input); err != nil {
+			logger.LogErrorWithStack(ctx, err)
+		}
+	}
+}
+
+// cpuFailRate は CPU がシーケンスに失敗する確率を返す
+func (gm *GameManager) cpuFailRate(level int) float64 {
+	return min(1, gm.cpuCfg.FailRate*float64(max(1, level)))
+}
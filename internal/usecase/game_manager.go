@@ -1,12 +1,12 @@
 package usecase
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"log"
-	"math"
-	"math/rand"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,6 +15,9 @@ import (
 	"github.com/Simo-C3/stego2-server/internal/domain/repository"
 	"github.com/Simo-C3/stego2-server/internal/domain/service"
 	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/config"
+	"github.com/Simo-C3/stego2-server/pkg/logger"
+	"github.com/Simo-C3/stego2-server/pkg/uuid"
 )
 
 type GameManager struct {
@@ -24,9 +27,21 @@ type GameManager struct {
 	roomRepo repository.RoomRepository
 	problem  repository.ProblemRepository
 	msg      service.MessageSender
+	session  repository.SessionRepository
+	replay   repository.ReplayRepository
+	match    repository.MatchRepository
+	stats    repository.StatsRepository
+	rating   repository.RatingRepository
+	board    repository.LeaderboardRepository
+	timer    service.Timer
+	cfg      *config.GameConfig
+	cpuCfg   *config.CPUConfig
+
+	// このインスタンスで問題の配布を予約した試合
+	reveals sync.Map
 }
 
-func NewGameManager(pub service.Publisher, sub service.Subscriber, repo repository.GameRepository, roomRepo repository.RoomRepository, problem repository.ProblemRepository, msg service.MessageSender) *GameManager {
+func NewGameManager(pub service.Publisher, sub service.Subscriber, repo repository.GameRepository, roomRepo repository.RoomRepository, problem repository.ProblemRepository, msg service.MessageSender, session repository.SessionRepository, replay repository.ReplayRepository, match repository.MatchRepository, stats repository.StatsRepository, rating repository.RatingRepository, board repository.LeaderboardRepository, timer service.Timer, cfg *config.GameConfig, cpuCfg *config.CPUConfig) *GameManager {
 	return &GameManager{
 		pub:      pub,
 		sub:      sub,
@@ -34,16 +49,70 @@ func NewGameManager(pub service.Publisher, sub service.Subscriber, repo reposito
 		roomRepo: roomRepo,
 		problem:  problem,
 		msg:      msg,
+		session:  session,
+		replay:   replay,
+		match:    match,
+		stats:    stats,
+		rating:   rating,
+		board:    board,
+		timer:    timer,
+		cfg:      cfg,
+		cpuCfg:   cpuCfg,
 	}
 }
 
 func (gm *GameManager) StartGame(ctx context.Context, roomID string, userID string) error {
-	err := gm.repo.EditGame(ctx, roomID, func(game *model.Game) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if game.BaseRoom.OwnerID != userID {
+		return model.ErrNotOwner
+	}
+	if game.Status != model.GameStatusPending {
+		return model.ErrGameIsStarted
+	}
+	if err := game.CanStart(); err != nil {
+		return err
+	}
+
+	// 空席を CPU で埋める
+	cpus, err := gm.fillCPU(ctx, game)
+	if err != nil {
+		return err
+	}
+
+	matchID, err := uuid.GenerateUUIDv7()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = gm.repo.EditGame(ctx, roomID, func(game *model.Game) error {
 		if game.BaseRoom.OwnerID != userID {
-			return errors.New("you are not owner")
+			return model.ErrNotOwner
+		}
+		if game.Status != model.GameStatusPending {
+			return model.ErrGameIsStarted
+		}
+		if err := game.CanStart(); err != nil {
+			return err
+		}
+
+		for _, cpu := range cpus {
+			game.Users[cpu.ID] = cpu
+		}
+
+		// チーム戦では未所属のユーザーをチームに振り分ける
+		game.FillTeams()
+		if game.IsTeamMode() && len(game.AliveTeams()) < 2 {
+			return model.ErrNotEnoughTeams
 		}
 
 		game.Status = model.GameStatusPlaying
+		game.MatchID = matchID
+		game.CountdownID = ""
+		game.Revealed = false
+		game.StartAt = int(time.Now().Add(model.GameStartDelay * time.Second).Unix())
 		return nil
 	})
 	if err != nil {
@@ -57,13 +126,39 @@ func (gm *GameManager) StartGame(ctx context.Context, roomID string, userID stri
 		return err
 	}
 
-	game, err := gm.repo.GetGameByID(ctx, roomID)
+	// ロビーで記録したメッセージは捨てて、ここからリプレイに記録する
+	if err := gm.replay.Reset(ctx, roomID); err != nil {
+		return err
+	}
+
+	game, err = gm.repo.GetGameByID(ctx, roomID)
 	if err != nil {
 		return err
 	}
 
-	start := time.Now().Add(5 * time.Second).Unix()
-	pm := &schema.PublishContent{
+	// 問題を配り、振り分けたチームをユーザーにも反映する
+	// 問題を見せるのは開始の合図の時刻になってから
+	for _, u := range game.Users {
+		seqs := u.Sequences
+		if len(seqs) == 0 {
+			seqs, err = gm.newSequences(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		team := u.Team
+		err := gm.editUser(ctx, roomID, u.ID, func(u *model.User) error {
+			u.Team = team
+			u.Sequences = seqs
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	start := int64(game.StartAt)
+	err = gm.publish(ctx, &schema.PublishContent{
 		RoomID: roomID,
 		Payload: schema.ChangeRoomState{
 			Type: schema.TypeChangeRoom,
@@ -76,400 +171,682 @@ func (gm *GameManager) StartGame(ctx context.Context, roomID string, userID stri
 				OwnerID:    game.BaseRoom.OwnerID,
 			},
 		},
-	}
-
-	pj, err := json.Marshal(pm)
+	})
 	if err != nil {
 		return err
 	}
 
-	err = gm.pub.Publish(ctx, "game", pj)
+	// 制限時間のカウントは開始の合図の後から始める
+	if game.BaseRoom.Duration > 0 {
+		if err := gm.timer.Start(ctx, roomID, game.BaseRoom.Duration, model.GameStartDelay); err != nil {
+			return err
+		}
+	}
+
+	gm.scheduleReveal(game)
+
+	return nil
+}
+
+// scheduleReveal は開始の合図の時刻に問題を配るように、このインスタンスで試合ごとに一度だけ予約する
+// 開始したインスタンスが落ちても配れるように、メッセージを受け取った全てのインスタンスで予約する
+func (gm *GameManager) scheduleReveal(game *model.Game) {
+	if game.Status != model.GameStatusPlaying || game.Revealed {
+		return
+	}
+	if _, loaded := gm.reveals.LoadOrStore(game.MatchID, struct{}{}); loaded {
+		return
+	}
+	go gm.revealSequences(game.ID, game.MatchID, time.Unix(int64(game.StartAt), 0))
+}
+
+// revealSequences は開始の合図の時刻に最初の問題を全員に配り、CPU を動かし始める
+// 配るのは Revealed を立てた1つのインスタンスだけ
+func (gm *GameManager) revealSequences(roomID, matchID string, startAt time.Time) {
+	defer gm.reveals.Delete(matchID)
+	time.Sleep(time.Until(startAt))
+
+	ctx := context.Background()
+	logger := logger.New()
+
+	var game *model.Game
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		game = nil
+		if g.Status != model.GameStatusPlaying || g.MatchID != matchID || g.Revealed {
+			return nil
+		}
+		g.Revealed = true
+		game = g
+		return nil
+	})
+	if errors.Is(err, model.ErrGameNotFound) {
+		return
+	}
 	if err != nil {
-		return err
+		logger.LogErrorWithStack(ctx, err)
+		return
+	}
+	if game == nil {
+		return
 	}
 
 	// 全員に問題を配布
 	status := make([]*schema.ChangeOtherUserState, 0, len(game.Users))
 	for _, user := range game.Users {
-		status = append(status, &schema.ChangeOtherUserState{
-			ID:       user.ID,
-			Name:     user.DisplayName,
-			Life:     user.Life,
-			Seq:      user.Sequences[0].Value,
-			InputSeq: "",
-			Rank:     0,
-		})
+		status = append(status, convertToUserState(user, 0))
 	}
-	publishContent := &schema.PublishContent{
+	err = gm.publish(ctx, &schema.PublishContent{
 		RoomID: roomID,
 		Payload: schema.Base{
 			Type:    schema.TypeChangeOtherUsersState,
 			Payload: status,
 		},
+	})
+	if err != nil {
+		logger.LogErrorWithStack(ctx, err)
+	}
+
+	for _, user := range game.Users {
+		if user.IsCPU {
+			go gm.runCPU(roomID, user.ID)
+			continue
+		}
+
+		seq, err := user.CurrentSequence()
+		if err != nil {
+			continue
+		}
+		err = gm.publish(ctx, &schema.PublishContent{
+			RoomID: roomID,
+			Payload: schema.Base{
+				Type: schema.TypeNextSeq,
+				Payload: schema.NextSeqEvent{
+					Value: seq.Value,
+					Type:  seq.Type,
+					Level: seq.Level,
+				},
+			},
+			IncludeUsers: []string{user.ID},
+		})
+		if err != nil {
+			logger.LogErrorWithStack(ctx, err)
+		}
+	}
+}
+
+func (gm *GameManager) TypeKey(ctx context.Context, gameID, userID string, key string) error {
+	if err := gm.checkStarted(ctx, gameID); err != nil {
+		return err
+	}
+
+	var finished bool
+	var user *model.User
+	err := gm.editUser(ctx, gameID, userID, func(u *model.User) error {
+		user = u
+		if u.IsFrozen(time.Now()) {
+			return model.ErrFrozen
+		}
+
+		var err error
+		finished, err = u.Type(key)
+		return err
+	})
+	if errors.Is(err, model.ErrMistype) {
+		// ミスタイプでストリークが途切れる
+		if err := gm.mistype(ctx, gameID, userID); err != nil {
+			return err
+		}
+		return gm.rejectInput(ctx, gameID, user, schema.RejectReasonMistype)
+	}
+	if errors.Is(err, model.ErrFrozen) {
+		return gm.rejectInput(ctx, gameID, user, schema.RejectReasonFrozen)
 	}
-	publishJSON, err := json.Marshal(publishContent)
 	if err != nil {
 		return err
 	}
-	if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
+
+	// 進捗を全体共有
+	publishContent := &schema.PublishContent{
+		RoomID: gameID,
+		Payload: schema.Base{
+			Type:    schema.TypeChangeOtherUserState,
+			Payload: convertToUserState(user, 0),
+		},
+		ExcludeUsers: []string{userID},
+	}
+	if err := gm.publish(ctx, publishContent); err != nil {
 		return err
 	}
 
+	// 最後まで入力されたらサーバー側でシーケンスを完了させる
+	if finished {
+		return gm.succeedSeq(ctx, gameID, userID)
+	}
+
 	return nil
 }
 
-func (gm *GameManager) TypeKey(ctx context.Context, gameID, userID string, key string) error {
-	user, err := gm.repo.GetUserByID(ctx, userID)
+// rejectInput は受け付けなかった入力を本人に伝え、サーバーが受け付けた入力に戻させる
+func (gm *GameManager) rejectInput(ctx context.Context, roomID string, user *model.User, reason string) error {
+	if user == nil || user.IsCPU {
+		return nil
+	}
+	seq, err := user.CurrentSequence()
 	if err != nil {
 		return err
 	}
-	// 進捗を全体共有
-	publishContent := &schema.PublishContent{
-		RoomID: gameID,
+
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeRejectInput,
+			Payload: &schema.RejectInput{
+				InputSeq: seq.Value[:user.Pos],
+				Reason:   reason,
+			},
+		},
+		IncludeUsers: []string{user.ID},
+	})
+}
+
+// checkStarted は開始の合図の前の入力を弾く
+func (gm *GameManager) checkStarted(ctx context.Context, roomID string) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if !game.HasStarted(time.Now()) {
+		return model.ErrGameIsNotStarted
+	}
+	// 配り損ねていたらここで配る
+	gm.scheduleReveal(game)
+	return nil
+}
+
+func (gm *GameManager) FinCurrentSeq(ctx context.Context, roomID, userID, cause string) error {
+	switch cause {
+	case "succeeded":
+		// 完了判定は TypeKey でサーバーが行うため、クライアントからの申告は無視する
+		return nil
+	case "failed":
+		if err := gm.checkStarted(ctx, roomID); err != nil {
+			return err
+		}
+		return gm.failSeq(ctx, roomID, userID)
+	default:
+		return errors.Errorf("unknown cause: %s", cause)
+	}
+}
+
+// AssignTeam はオーナーがロビーでユーザーをチームに割り当てる
+func (gm *GameManager) AssignTeam(ctx context.Context, roomID, userID, targetUserID string, team int) error {
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		if g.BaseRoom.OwnerID != userID {
+			return model.ErrNotOwner
+		}
+		return g.AssignTeam(targetUserID, team)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = gm.repo.EditUser(ctx, targetUserID, func(u *model.User) error {
+		u.Team = team
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
 		Payload: schema.Base{
-			Type: schema.TypeChangeOtherUserState,
-			Payload: schema.ChangeOtherUserState{
-				ID:       userID,
-				Name:     user.DisplayName,
-				Life:     user.Life,
-				Seq:      user.Sequences[0].Value,
-				InputSeq: key,
-				Rank:     0,
+			Type: schema.TypeChangeTeam,
+			Payload: &schema.ChangeTeam{
+				UserID: targetUserID,
+				Team:   team,
 			},
 		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publishLobby(ctx, roomID)
+}
+
+// ChangeTarget は攻撃対象の選び方を変更する
+func (gm *GameManager) ChangeTarget(ctx context.Context, roomID, userID, mode, targetUserID string) error {
+	m, err := model.NewTargetMode(mode)
+	if err != nil {
+		return err
+	}
+
+	if m == model.TargetModeUser {
+		game, err := gm.repo.GetGameByID(ctx, roomID)
+		if err != nil {
+			return err
+		}
+		if _, ok := game.Users[targetUserID]; !ok || targetUserID == userID {
+			return model.ErrInvalidTarget
+		}
+	} else {
+		targetUserID = ""
+	}
+
+	var user *model.User
+	err = gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		u.TargetMode = m
+		u.TargetUserID = targetUserID
+		user = u
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type:    schema.TypeChangeOtherUserState,
+			Payload: convertToUserState(user, 0),
+		},
 		ExcludeUsers: []string{userID},
+	})
+}
+
+// succeedSeq は入力し終えたシーケンスの効果を発動し、次の問題を配布する
+func (gm *GameManager) succeedSeq(ctx context.Context, roomID, userID string) error {
+	var user *model.User
+	err := gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		user = u
+		if u.Life <= 0 {
+			return nil
+		}
+
+		seq, err := u.CurrentSequence()
+		if err != nil {
+			return err
+		}
+		u.Streak++
+		u.Completed++
+		u.CountLevel(seq.Level)
+		u.Score += seq.Level * 10 * u.Multiplier(gm.cfg.StreakThresholds)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if user.Life <= 0 {
+		return nil
+	}
+
+	if err := gm.publishStreak(ctx, roomID, user); err != nil {
+		return err
+	}
+
+	seq, err := user.CurrentSequence()
+	if err != nil {
+		return err
+	}
+
+	if sp, ok := specialSequences[seq.Type]; ok {
+		err = sp.apply(gm, ctx, roomID, user)
+	} else {
+		err = gm.attack(ctx, roomID, user, seq)
+	}
+	if err != nil {
+		return err
+	}
+
+	if over, err := gm.checkGameOver(ctx, roomID); err != nil || over {
+		return err
+	}
+
+	return gm.nextSeq(ctx, roomID, userID)
+}
+
+// attack は生きている他のユーザーを攻撃する
+func (gm *GameManager) attack(ctx context.Context, roomID string, user *model.User, seq *model.Sequence) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	attacker, ok := game.Users[user.ID]
+	if !ok {
+		return errors.Errorf("user not found in game: %s", user.ID)
+	}
+
+	// 攻撃対象を選ぶ
+	target := game.SelectTarget(attacker)
+	if target == nil {
+		return nil
+	}
+
+	// 攻撃力を計算
+	damage := seq.Level * attacker.Multiplier(gm.cfg.StreakThresholds) * 20
+
+	// ダメージ 2 倍の効果を消費する
+	if attacker.DoubleDamage {
+		err = gm.editUser(ctx, roomID, attacker.ID, func(u *model.User) error {
+			u.DoubleDamage = false
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		damage *= 2
+
+		err = gm.publishEffect(ctx, roomID, schema.TypeDoubleDamage, &schema.EffectEvent{
+			UserID: attacker.ID,
+			State:  schema.EffectStateTriggered,
+			From:   attacker.ID,
+			To:     target.ID,
+			Damage: damage,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 攻撃対象のシールド・反射を消費する
+	var blocked, reflected bool
+	err = gm.editUser(ctx, roomID, target.ID, func(u *model.User) error {
+		blocked, reflected = u.Shield, !u.Shield && u.Reflect
+		if blocked {
+			u.Shield = false
+		} else if reflected {
+			u.Reflect = false
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	publishJSON, err := json.Marshal(publishContent)
+
+	switch {
+	case blocked:
+		return gm.publishEffect(ctx, roomID, schema.TypeShield, &schema.EffectEvent{
+			UserID: target.ID,
+			State:  schema.EffectStateTriggered,
+			From:   attacker.ID,
+			To:     target.ID,
+			Damage: damage,
+		})
+	case reflected:
+		err = gm.publishEffect(ctx, roomID, schema.TypeReflect, &schema.EffectEvent{
+			UserID: target.ID,
+			State:  schema.EffectStateTriggered,
+			From:   target.ID,
+			To:     attacker.ID,
+			Damage: damage,
+		})
+		if err != nil {
+			return err
+		}
+		return gm.damage(ctx, roomID, target.ID, attacker.ID, damage)
+	default:
+		return gm.damage(ctx, roomID, attacker.ID, target.ID, damage)
+	}
+}
+
+// damage は攻撃対象の難易度を上げる
+func (gm *GameManager) damage(ctx context.Context, roomID, fromUserID, toUserID string, damage int) error {
+	var newDifficult int
+	err := gm.editUser(ctx, roomID, toUserID, func(u *model.User) error {
+		u.Difficult += damage
+		u.DamageReceived += damage
+		u.LastAttackedBy = fromUserID
+		newDifficult = u.Difficult
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = gm.editUser(ctx, roomID, fromUserID, func(u *model.User) error {
+		u.DamageDealt += damage
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Publish: ChangeWordDifficult
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeChangeWordDifficult,
+			Payload: &schema.ChangeWordDifficult{
+				Difficult: newDifficult,
+				Cause:     "damage",
+			},
+		},
+		IncludeUsers: []string{toUserID},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Publish: AttackEvent
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeAttack,
+			Payload: &schema.AttackEvent{
+				From:   fromUserID,
+				To:     toUserID,
+				Damage: damage,
+			},
+		},
+	})
+}
+
+// failSeq はシーケンスの失敗を処理し、ライフを減らす
+func (gm *GameManager) failSeq(ctx context.Context, roomID, userID string) error {
+	var user *model.User
+	err := gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		if u.Life <= 0 {
+			return nil
+		}
+		u.Life--
+		u.Streak = 0
+		user = u
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 既に死亡している
+	if user == nil {
+		return nil
+	}
+
+	if err := gm.publishStreak(ctx, roomID, user); err != nil {
+		return err
+	}
+
+	if user.Life <= 0 {
+		if over, err := gm.die(ctx, roomID, userID); err != nil || over {
+			return err
+		}
+	} else {
+		// Publish: ChangeOtherUserState
+		err := gm.publish(ctx, &schema.PublishContent{
+			RoomID: roomID,
+			Payload: schema.Base{
+				Type:    schema.TypeChangeOtherUserState,
+				Payload: convertToUserState(user, 0),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return gm.nextSeq(ctx, roomID, userID)
+}
+
+// die はライフが無くなったユーザーの死亡時刻と順位を確定し、ゲームの終了を判定する
+func (gm *GameManager) die(ctx context.Context, roomID, userID string) (bool, error) {
+	var user *model.User
+	deadAt := int(time.Now().Unix())
+	err := gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		u.DeadAt = deadAt
+		user = u
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// 順位を計算
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return false, err
+	}
+	rank, err := game.GetRanking(userID)
+	if err != nil {
+		return false, err
+	}
+
+	// Publish: ChangeOtherUserState
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type:    schema.TypeChangeOtherUserState,
+			Payload: convertToUserState(user, rank),
+		},
+	})
 	if err != nil {
-		return errors.WithStack(err)
-	}
-
-	if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return gm.checkGameOver(ctx, roomID)
 }
 
-func (gm *GameManager) FinCurrentSeq(ctx context.Context, roomID, userID, cause string) error {
-	user, err := gm.repo.GetUserByID(ctx, userID)
+// checkGameOver はルームの終了条件を満たしていればゲームを終了する
+func (gm *GameManager) checkGameOver(ctx context.Context, roomID string) (bool, error) {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
 	if err != nil {
-		return err
+		return false, err
 	}
-	if user.Life <= 0 {
-		return nil
+	if game.Status != model.GameStatusPlaying {
+		return true, nil
+	}
+	if !game.WinCondition().IsOver(game) {
+		return false, nil
 	}
 
-	if cause == "succeeded" {
-		seq := user.Sequences[0]
-		if seq.Type == "default" {
-			// 誰かを攻撃
-			// ルームから生きてるユーザーを取得
-			game, err := gm.repo.GetGameByID(ctx, roomID)
-			if err != nil {
-				return err
-			}
-			userIDs := make([]string, 0, len(game.Users))
-			for id, user := range game.Users {
-				// 自分以外でライフが残っているユーザーを攻撃対象にする
-				if user.Life > 0 && id != userID {
-					userIDs = append(userIDs, id)
-				}
-			}
-
-			if len(userIDs) == 0 {
-				return nil
-			}
-
-			// ランダムに攻撃対象を選ぶ
-			attackedUserIndex := rand.Intn(len(userIDs))
-
-			// 攻撃力を計算
-			damage := user.Sequences[0].Level * int(math.Max(1, float64(user.Streak/10))) * 20
-
-			// User を更新
-			var newDifficult int
-			targetUserID := userIDs[attackedUserIndex]
-			err = gm.repo.EditUser(ctx, targetUserID, func(u *model.User) error {
-				u.Difficult += damage
-				newDifficult = u.Difficult
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-
-			err = gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
-				g.Users[targetUserID].Difficult += damage
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-
-			// Publish: ChangeWordDifficult
-			publishContent := &schema.PublishContent{
-				RoomID: roomID,
-				Payload: schema.Base{
-					Type: schema.TypeChangeWordDifficult,
-					Payload: &schema.ChangeWordDifficult{
-						Difficult: newDifficult,
-						Cause:     "damage",
-					},
-				},
-				IncludeUsers: []string{targetUserID},
-			}
-			publishJSON, err := json.Marshal(publishContent)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
-				return err
-			}
-
-			// Publish: AttackEvent
-			publishContent = &schema.PublishContent{
-				RoomID: roomID,
-				Payload: schema.Base{
-					Type: schema.TypeAttack,
-					Payload: &schema.AttackEvent{
-						From:   userID,
-						To:     targetUserID,
-						Damage: damage,
-					},
-				},
-			}
-			publishJSON, err = json.Marshal(publishContent)
-			if err != nil {
-				return errors.WithStack(err)
-			}
-			if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
-				return err
-			}
-		} else if seq.Type == "heal" {
-			// 回復
-			var newDifficult int
-			err = gm.repo.EditUser(ctx, userID, func(u *model.User) error {
-				u.Difficult -= 200
-				if u.Difficult < 0 {
-					u.Difficult = 0
-				}
-				newDifficult = u.Difficult
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-
-			// gameのusersも更新
-			err = gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
-				g.Users[userID].Difficult = newDifficult
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-
-			event := schema.Base{
-				Type: schema.TypeChangeWordDifficult,
-				Payload: &schema.ChangeWordDifficult{
-					Difficult: newDifficult,
-					Cause:     "heal",
-				},
-			}
-
-			if err := gm.msg.Send(ctx, userID, &event); err != nil {
-				return err
-			}
+	return true, gm.finishGame(ctx, roomID)
+}
 
+// finishGame はゲームを終了し、結果を配信して後片付けをする
+// 複数のインスタンスから同時に呼ばれても、終了処理は一度だけ行われる
+func (gm *GameManager) finishGame(ctx context.Context, roomID string) error {
+	// gameのstatusを更新
+	var game *model.Game
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		if g.Status != model.GameStatusPlaying {
+			return model.ErrGameIsNotPlaying
 		}
-	} else if cause == "failed" {
-		var user *model.User
-		err = gm.repo.EditUser(ctx, userID, func(u *model.User) error {
-			u.Life--
-			user = u
-			return nil
-		})
-		if err != nil {
-			return err
-		}
+		g.Status = model.GameStatusFinished
+		game = g
+		return nil
+	})
+	if errors.Is(err, model.ErrGameIsNotPlaying) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-		// gameのusersも更新
-		err = gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
-			g.Users[userID].Life--
-			return nil
-		})
-		if err != nil {
-			return err
+	if game.BaseRoom.Duration > 0 {
+		if err := gm.timer.Stop(ctx, roomID); err != nil {
+			log.Println("failed to stop timer:", err)
 		}
+	}
 
-		if user.Life <= 0 {
-			//死亡
-			deadAt := int(time.Now().Unix())
-			err := gm.repo.EditUser(ctx, userID, func(u *model.User) error {
-				u.DeadAt = deadAt
-				return nil
-			})
-			if err != nil {
-				return err
-			}
-
-			// gameのusersも更新
-			err = gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
-				g.Users[userID].DeadAt = deadAt
-				return nil
-			})
-			if err != nil {
-				return err
-			}
+	rs, err := game.GetResult()
+	if err != nil {
+		return err
+	}
 
-			// 順位を計算
-			game, err := gm.repo.GetGameByID(ctx, roomID)
-			if err != nil {
-				return err
-			}
-			rank, err := game.GetRanking(userID)
-			if err != nil {
-				return err
-			}
+	finishedAt := time.Now()
+	ratings, err := gm.updateRatings(ctx, game, rs, finishedAt)
+	if err != nil {
+		log.Println("failed to update ratings:", err)
+	}
 
-			// Publish: ChangeOtherUserState
-			user, err := gm.repo.GetUserByID(ctx, userID)
-			if err != nil {
-				return err
-			}
-			publishContent := &schema.PublishContent{
-				RoomID: roomID,
-				Payload: schema.Base{
-					Type: schema.TypeChangeOtherUserState,
-					Payload: &schema.ChangeOtherUserState{
-						ID:       user.ID,
-						Name:     user.DisplayName,
-						Life:     user.Life,
-						Seq:      user.Sequences[0].Value,
-						InputSeq: user.Sequences[0].Value[:user.Pos],
-						Rank:     rank,
-					},
-				},
-			}
-			publishJSON, err := json.Marshal(publishContent)
-			if err != nil {
-				return err
-			}
+	// Publish: Result
+	results := make([]*schema.Result, 0, len(rs))
+	for _, r := range rs {
+		res := schema.NewResult(r.UserID, r.Rank, r.DisplayName, r.Team, r.TeamRank)
+		if c, ok := ratings[r.UserID]; ok {
+			res.Rating = c.After.Rating
+			res.RatingDelta = c.Delta()
+		}
+		results = append(results, res)
+	}
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type:    schema.TypeResult,
+			Payload: results,
+		},
+	})
+	if err != nil {
+		return err
+	}
 
-			if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
-				return err
-			}
+	// publish content
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: &schema.ChangeRoomState{
+			Type: schema.TypeChangeRoom,
+			Payload: schema.ChangeRoomStatePayload{
+				UserNum:    len(game.Users),
+				Status:     model.RoomStatusFinish,
+				StartedAt:  nil,
+				StartDelay: model.GameStartDelay,
+				MaxUserNum: game.BaseRoom.MaxUserNum,
+				OwnerID:    game.BaseRoom.OwnerID,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
 
-			// 2位まで決まったら終了
-			if rank <= 2 {
-				// gameのstatusを更新
-				var rs []*model.GameResult
-
-				err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
-					g.Status = model.GameStatusFinished
-					var err error
-					rs, err = game.GetResult()
-					if err != nil {
-						return errors.WithStack(err)
-					}
-
-					return nil
-				})
-				if err != nil {
-					return err
-				}
-
-				// Publish: Result
-				results := make([]*schema.Result, 0, len(rs))
-				for _, r := range rs {
-					results = append(results, schema.NewResult(r.UserID, r.Rank, r.DisplayName))
-				}
-				publishContent := &schema.PublishContent{
-					RoomID: roomID,
-					Payload: schema.Base{
-						Type:    schema.TypeResult,
-						Payload: results,
-					},
-				}
-				publishJSON, err := json.Marshal(publishContent)
-				if err != nil {
-					return err
-				}
-				if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
-					return err
-				}
-
-				// publish content
-				p := &schema.PublishContent{
-					RoomID: roomID,
-					Payload: &schema.ChangeRoomState{
-						Type: schema.TypeChangeRoom,
-						Payload: schema.ChangeRoomStatePayload{
-							UserNum:    len(game.Users),
-							Status:     model.RoomStatusFinish,
-							StartedAt:  nil,
-							StartDelay: model.GameStartDelay,
-							MaxUserNum: game.BaseRoom.MaxUserNum,
-							OwnerID:    game.BaseRoom.OwnerID,
-						},
-					},
-				}
-
-				publishJSON, err = json.Marshal(p)
-				if err != nil {
-					return err
-				}
-				// publish
-				if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
-					return err
-				}
-
-				for _, user := range game.Users {
-					if err := gm.repo.DeleteUser(ctx, user.ID); err != nil {
-						log.Println("failed to delete user:", err)
-					}
-				}
-				if err := gm.repo.DeleteGame(ctx, roomID); err != nil {
-					return err
-				}
-
-				return nil
-			}
-			return nil
-		} else {
-			user, err := gm.repo.GetUserByID(ctx, userID)
-			if err != nil {
-				return err
-			}
-			// Publish: ChangeOtherUserState
-			publishContent := &schema.PublishContent{
-				RoomID: roomID,
-				Payload: schema.Base{
-					Type: schema.TypeChangeOtherUserState,
-					Payload: &schema.ChangeOtherUserState{
-						ID:       user.ID,
-						Name:     user.DisplayName,
-						Life:     user.Life,
-						Seq:      user.Sequences[0].Value,
-						InputSeq: user.Sequences[0].Value[:user.Pos],
-						Rank:     0,
-					},
-				},
-			}
-			publishJSON, err := json.Marshal(publishContent)
-			if err != nil {
-				return err
-			}
+	if err := gm.roomRepo.UpdateRoom(ctx, &model.Room{
+		ID:     roomID,
+		Status: model.RoomStatusFinish,
+	}); err != nil {
+		log.Println("failed to update room:", err)
+	}
 
-			if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
-				return err
-			}
-		}
+	if err := gm.saveReplay(ctx, game); err != nil {
+		log.Println("failed to save replay:", err)
 	}
+	if err := gm.match.SaveMatch(ctx, model.NewMatchRecord(game, rs, finishedAt)); err != nil {
+		log.Println("failed to save match:", err)
+	}
+	stats := model.NewGameStats(game, rs, finishedAt)
+	if err := gm.stats.AddGameStats(ctx, stats); err != nil {
+		log.Println("failed to save stats:", err)
+	}
+	if err := gm.updateLeaderboards(ctx, game, stats, finishedAt); err != nil {
+		log.Println("failed to update leaderboards:", err)
+	}
+
+	// 再戦できるように game と users は残しておく (Redis の TTL で消える)
+	return nil
+}
 
+// nextSeq は次の問題を取得してユーザーに配布する
+func (gm *GameManager) nextSeq(ctx context.Context, roomID, userID string) error {
 	// levelを算出
-	user, err = gm.repo.GetUserByID(ctx, userID)
+	user, err := gm.repo.GetUserByID(ctx, userID)
 	if err != nil {
 		return err
 	}
@@ -479,9 +856,9 @@ func (gm *GameManager) FinCurrentSeq(ctx context.Context, roomID, userID, cause
 		level = 10
 	}
 
-	isHeal := rand.Intn(100) < 5 // 5%
-	if isHeal {
-		level += 3
+	typ := rollSequenceType()
+	if sp, ok := specialSequences[typ]; ok {
+		level += sp.levelBonus
 		if level > 10 {
 			level = 10
 		}
@@ -492,31 +869,20 @@ func (gm *GameManager) FinCurrentSeq(ctx context.Context, roomID, userID, cause
 	if err != nil {
 		return err
 	}
-	problem := problems[0]
-	typ := "default"
-	if isHeal {
-		typ = "heal"
+	if len(problems) == 0 {
+		return errors.Errorf("no problem found: level=%d", level)
 	}
+	problem := problems[0]
 	nextSeq := &model.Sequence{
 		Value: problem.CollectSentence,
 		Level: problem.Level,
 		Type:  typ,
 	}
 
-	err = gm.repo.EditUser(ctx, userID, func(u *model.User) error {
+	err = gm.editUser(ctx, roomID, userID, func(u *model.User) error {
 		u.Sequences = append(u.Sequences[1:], nextSeq)
 		u.Pos = 0
-		return nil
-	})
-	if err != nil {
-		return err
-	}
-
-	// gameのusersも更新
-	err = gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
-		g.Users[userID].Sequences = append(g.Users[userID].Sequences[1:], nextSeq)
-		g.Users[userID].Pos = 0
-
+		u.MaxPos = 0
 		return nil
 	})
 	if err != nil {
@@ -524,7 +890,7 @@ func (gm *GameManager) FinCurrentSeq(ctx context.Context, roomID, userID, cause
 	}
 
 	// Publish: NextSeq
-	publishContent := &schema.PublishContent{
+	return gm.publish(ctx, &schema.PublishContent{
 		RoomID: roomID,
 		Payload: schema.Base{
 			Type: schema.TypeNextSeq,
@@ -535,19 +901,115 @@ func (gm *GameManager) FinCurrentSeq(ctx context.Context, roomID, userID, cause
 			},
 		},
 		IncludeUsers: []string{userID},
+	})
+}
+
+// mistype はミスタイプを数え、ストリークを 0 に戻す
+func (gm *GameManager) mistype(ctx context.Context, roomID, userID string) error {
+	var user *model.User
+	var changed bool
+	err := gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		u.Mistypes++
+		changed = u.Streak != 0
+		u.Streak = 0
+		user = u
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
 	}
-	publishJSON, err := json.Marshal(publishContent)
+
+	return gm.publishStreak(ctx, roomID, user)
+}
+
+func (gm *GameManager) publishStreak(ctx context.Context, roomID string, user *model.User) error {
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeStreak,
+			Payload: &schema.StreakEvent{
+				UserID:     user.ID,
+				Streak:     user.Streak,
+				Multiplier: user.Multiplier(gm.cfg.StreakThresholds),
+			},
+		},
+	})
+}
+
+// newSequences はゲームの開始時に配る問題を取得する
+func (gm *GameManager) newSequences(ctx context.Context) ([]*model.Sequence, error) {
+	problems, err := gm.problem.GetProblems(ctx, 1, 2)
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, err
+	}
+
+	seqs := make([]*model.Sequence, 0, len(problems))
+	for _, problem := range problems {
+		seqs = append(seqs, &model.Sequence{
+			Value: problem.CollectSentence,
+			Level: problem.Level,
+			Type:  model.SequenceTypeDefault,
+		})
 	}
+	return seqs, nil
+}
 
-	if err := gm.pub.Publish(ctx, "game", publishJSON); err != nil {
+// editUser はユーザーと game の users の両方に同じ編集を適用する
+func (gm *GameManager) editUser(ctx context.Context, roomID, userID string, fn func(*model.User) error) error {
+	if err := gm.repo.EditUser(ctx, userID, fn); err != nil {
 		return err
 	}
-	return nil
+
+	// gameのusersも更新
+	return gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		u, ok := g.Users[userID]
+		if !ok {
+			return errors.Errorf("user not found in game: %s", userID)
+		}
+		return fn(u)
+	})
+}
+
+func convertToUserState(user *model.User, rank int) *schema.ChangeOtherUserState {
+	return &schema.ChangeOtherUserState{
+		ID:       user.ID,
+		Name:     user.DisplayName,
+		Life:     user.Life,
+		Seq:      user.CurrentValue(),
+		InputSeq: user.InputSeq(),
+		Rank:     rank,
+		Streak:   user.Streak,
+		Team:     user.Team,
+		Target: schema.Target{
+			Mode:   cmp.Or(user.TargetMode, model.TargetModeRandom).String(),
+			UserID: user.TargetUserID,
+		},
+		Connection: convertToConnection(user),
+	}
+}
+
+func convertToConnection(user *model.User) string {
+	if user.IsConnected() {
+		return model.ConnectionConnected
+	}
+	return model.ConnectionDisconnected
+}
+
+func (gm *GameManager) publish(ctx context.Context, content *schema.PublishContent) error {
+	publishJSON, err := json.Marshal(content)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	gm.recordPublish(ctx, content)
+
+	return gm.pub.Publish(ctx, "game", publishJSON)
 }
 
-func (gm *GameManager) Join(ctx context.Context, roomID, userID string) error {
+func (gm *GameManager) Join(ctx context.Context, roomID, userID, connID string) error {
 	game, err := gm.repo.GetGameByID(ctx, roomID)
 	if err != nil {
 		return err
@@ -578,22 +1040,42 @@ func (gm *GameManager) Join(ctx context.Context, roomID, userID string) error {
 		return err
 	}
 
+	now := time.Now().UnixMilli()
+	ratings, err := gm.rating.GetRatings(ctx, []string{userID})
+	if err != nil {
+		return err
+	}
+
+	// 問題はゲームの開始時に配る
 	var user *model.User
 	err = gm.repo.EditUser(ctx, userID, func(u *model.User) error {
-		problems, err := gm.problem.GetProblems(ctx, 1, 2)
-		if err != nil {
-			return err
+		u.Rating = ratings[userID].Rating
+		u.ConnID = connID
+		u.DisconnectedAt = 0
+		if u.JoinedAt == 0 {
+			u.JoinedAt = now
 		}
+		user = u
 
-		for _, problem := range problems {
-			u.Sequences = append(u.Sequences, &model.Sequence{
-				Value: problem.CollectSentence,
-				Level: problem.Level,
-			})
-		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
+	// 再接続用のトークンを発行する
+	if user.ReconnectToken != "" {
+		if err := gm.session.RevokeReconnectToken(ctx, user.ReconnectToken); err != nil {
+			log.Println("failed to revoke reconnect token:", err)
+		}
+	}
+	token, err := gm.session.IssueReconnectToken(ctx, roomID, userID, reconnectTokenTTL)
+	if err != nil {
+		return err
+	}
+	err = gm.repo.EditUser(ctx, userID, func(u *model.User) error {
+		u.ReconnectToken = token
 		user = u
-
 		return nil
 	})
 	if err != nil {
@@ -610,16 +1092,43 @@ func (gm *GameManager) Join(ctx context.Context, roomID, userID string) error {
 	}
 
 	if err = gm.msg.Send(ctx, userID, &schema.Base{
-		Type: schema.TypeNextSeq,
-		Payload: schema.NextSeqEvent{
-			Value: user.Sequences[0].Value,
-			Type:  "default",
-			Level: user.Sequences[0].Level,
+		Type: schema.TypeReconnectToken,
+		Payload: schema.ReconnectToken{
+			Token:       token,
+			GracePeriod: int(gm.cfg.ReconnectGracePeriod.Seconds()),
 		},
 	}); err != nil {
 		return err
 	}
 
+	// 開始の合図の後に入り直した場合は入力中の問題を送る
+	// まだ配り終えていなければ、配るときに一緒に送られる
+	gm.scheduleReveal(game)
+	if game.HasStarted(time.Now()) && game.Revealed {
+		seq, err := user.CurrentSequence()
+		if err != nil {
+			return err
+		}
+		if err = gm.msg.Send(ctx, userID, &schema.Base{
+			Type: schema.TypeNextSeq,
+			Payload: schema.NextSeqEvent{
+				Value: seq.Value,
+				Type:  seq.Type,
+				Level: seq.Level,
+			},
+		}); err != nil {
+			return err
+		}
+	}
+
+	// ロビーでは参加者の一覧を配信し、新しい参加者が準備完了でなければカウントダウンを止める
+	if game.Status == model.GameStatusPending {
+		if err := gm.publishLobby(ctx, roomID); err != nil {
+			return err
+		}
+		return gm.checkAutoStart(ctx, roomID)
+	}
+
 	return nil
 }
 
@@ -633,29 +1142,55 @@ func (gm *GameManager) SubscribeMessage(ctx context.Context, topic string) {
 			continue
 		}
 
-		game, err := gm.repo.GetGameByID(ctx, content.RoomID)
-		if err != nil {
+		// ゲームが消えていても、接続を閉じるユーザーには送る
+		if game, err := gm.repo.GetGameByID(ctx, content.RoomID); err == nil {
+			gm.broadcast(ctx, game, &content)
+		}
+		gm.closeUsers(ctx, &content)
+	}
+}
+
+// broadcast はゲームの参加者と観戦者のうち、配信先に含まれるユーザーに送る
+func (gm *GameManager) broadcast(ctx context.Context, game *model.Game, content *schema.PublishContent) {
+	gm.scheduleReveal(game)
+	userIDs := make([]string, 0, len(game.Users))
+
+	includeUsers := content.IncludeUsers
+	excludeUsers := content.ExcludeUsers
+
+	for _, user := range game.Users {
+		if slices.Contains(excludeUsers, user.ID) {
 			continue
 		}
-		userIDs := make([]string, 0, len(game.Users))
 
-		includeUsers := content.IncludeUsers
-		excludeUsers := content.ExcludeUsers
+		if len(includeUsers) > 0 && !slices.Contains(includeUsers, user.ID) {
+			continue
+		}
 
-		for _, user := range game.Users {
-			if slices.Contains(excludeUsers, user.ID) {
-				continue
-			}
+		userIDs = append(userIDs, user.ID)
+	}
 
-			if len(includeUsers) > 0 && !slices.Contains(includeUsers, user.ID) {
-				continue
+	// 観戦者には個人宛て以外のメッセージを送る
+	if len(includeUsers) == 0 {
+		for _, id := range game.Spectators {
+			if !slices.Contains(excludeUsers, id) {
+				userIDs = append(userIDs, id)
 			}
-
-			userIDs = append(userIDs, user.ID)
 		}
+	}
 
-		if err := gm.msg.Broadcast(ctx, userIDs, content.Payload); err != nil {
-			log.Println("failed to broadcast message:", err)
+	if err := gm.msg.Broadcast(ctx, userIDs, content.Payload); err != nil {
+		log.Println("failed to broadcast message:", err)
+	}
+}
+
+// closeUsers は接続を閉じるユーザーに最後のメッセージを送ってから登録を解除する
+// 別のインスタンスに接続しているユーザーには何もしない
+func (gm *GameManager) closeUsers(ctx context.Context, content *schema.PublishContent) {
+	for _, id := range content.CloseUsers {
+		if err := gm.msg.Send(ctx, id, content.Payload); err != nil {
+			continue
 		}
+		gm.msg.Unregister(id)
 	}
 }
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+type fakeSubscriber struct {
+	ch chan *redis.Message
+}
+
+func (s *fakeSubscriber) Subscribe(ctx context.Context, topic string) <-chan *redis.Message {
+	return s.ch
+}
+
+// fakeGameRepository はゲームが1つもない GameRepository
+type fakeGameRepository struct {
+	repository.GameRepository
+}
+
+func (r *fakeGameRepository) GetGameByID(ctx context.Context, id string) (*model.Game, error) {
+	return nil, errors.WithStack(model.ErrGameNotFound)
+}
+
+type fakeMessageSender struct {
+	mu           sync.Mutex
+	sent         []string
+	broadcasts   [][]string
+	unregistered []string
+}
+
+func (s *fakeMessageSender) Send(ctx context.Context, to string, data interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, to)
+	return nil
+}
+
+func (s *fakeMessageSender) Broadcast(ctx context.Context, ids []string, data interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.broadcasts = append(s.broadcasts, ids)
+	return nil
+}
+
+func (s *fakeMessageSender) Unregister(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unregistered = append(s.unregistered, userID)
+}
+
+func TestSubscribeMessageClosesUsersOfDeletedRoom(t *testing.T) {
+	sub := &fakeSubscriber{ch: make(chan *redis.Message, 1)}
+	msg := &fakeMessageSender{}
+	gm := &GameManager{
+		sub:  sub,
+		repo: &fakeGameRepository{},
+		msg:  msg,
+	}
+
+	data, err := json.Marshal(&schema.PublishContent{
+		RoomID: "deleted",
+		Payload: schema.Base{
+			Type:    schema.TypeKicked,
+			Payload: &schema.Kicked{Reason: schema.KickReasonClosed},
+		},
+		ExcludeUsers: []string{"a", "b"},
+		CloseUsers:   []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.ch <- &redis.Message{Payload: string(data)}
+	close(sub.ch)
+
+	gm.SubscribeMessage(context.Background(), "game")
+
+	want := []string{"a", "b"}
+	if !slices.Equal(msg.sent, want) {
+		t.Errorf("sent = %v, want %v", msg.sent, want)
+	}
+	if !slices.Equal(msg.unregistered, want) {
+		t.Errorf("unregistered = %v, want %v", msg.unregistered, want)
+	}
+	if len(msg.broadcasts) != 0 {
+		t.Errorf("broadcasts = %v, want none", msg.broadcasts)
+	}
+}
@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// SubscribeTimer はタイマーサービスからの通知を受け取り、残り時間の配信とゲームの終了を行う
+func (gm *GameManager) SubscribeTimer(ctx context.Context) {
+	ch := gm.sub.Subscribe(ctx, schema.TopicTimer)
+	for msg := range ch {
+		var n schema.TimerNotification
+		if err := json.Unmarshal([]byte(msg.Payload), &n); err != nil {
+			log.Println("failed to unmarshal timer notification:", err)
+			continue
+		}
+
+		if err := gm.tick(ctx, n.GameID, n.Remaining); err != nil {
+			log.Println("failed to handle timer notification:", err)
+		}
+	}
+}
+
+func (gm *GameManager) tick(ctx context.Context, roomID string, remaining int) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if game.Status != model.GameStatusPlaying {
+		return nil
+	}
+
+	// 通知は全インスタンスに届くため、Publish せずにこのインスタンスの接続にだけ送る
+	userIDs := make([]string, 0, len(game.Users)+len(game.Spectators))
+	for id := range game.Users {
+		userIDs = append(userIDs, id)
+	}
+	userIDs = append(userIDs, game.Spectators...)
+	if err := gm.msg.Broadcast(ctx, userIDs, &schema.Base{
+		Type: schema.TypeRemainingTime,
+		Payload: &schema.RemainingTime{
+			Remaining: remaining,
+		},
+	}); err != nil {
+		return err
+	}
+
+	if remaining <= 0 {
+		return gm.finishGame(ctx, roomID)
+	}
+	return nil
+}
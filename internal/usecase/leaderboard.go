@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+// updateLeaderboards は1試合分の成績で勝利数と最高 WPM のランキングを更新する
+func (gm *GameManager) updateLeaderboards(ctx context.Context, game *model.Game, stats []*model.UserStats, now time.Time) error {
+	for _, s := range stats {
+		u, ok := game.Users[s.UserID]
+		if !ok {
+			continue
+		}
+
+		if s.Wins > 0 {
+			if err := gm.board.IncrScore(ctx, model.LeaderboardKindWins, u.ID, u.DisplayName, float64(s.Wins), now); err != nil {
+				return err
+			}
+		}
+		if s.BestWPM > 0 {
+			if err := gm.board.MaxScore(ctx, model.LeaderboardKindWPM, u.ID, u.DisplayName, s.BestWPM, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/logger"
+)
+
+// Leave はユーザーがルームから抜ける
+// ロビーではユーザーを取り除き、ゲーム中は棄権として死亡させる
+func (gm *GameManager) Leave(ctx context.Context, roomID, userID string) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	switch game.Status {
+	case model.GameStatusPending, model.GameStatusFinished:
+		return gm.leaveLobby(ctx, roomID, userID)
+	case model.GameStatusPlaying:
+		return gm.forfeit(ctx, roomID, userID)
+	}
+	return nil
+}
+
+// watchDisconnect は切断の猶予が過ぎても戻らないユーザーをルームから抜けさせる
+func (gm *GameManager) watchDisconnect(roomID, userID string, disconnectedAt int64) {
+	time.Sleep(gm.cfg.ReconnectGracePeriod)
+
+	ctx := context.Background()
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return
+	}
+	// 再接続した、または既に取り除かれている
+	u, ok := game.Users[userID]
+	if !ok || u.DisconnectedAt != disconnectedAt {
+		return
+	}
+
+	if err := gm.Leave(ctx, roomID, userID); err != nil {
+		logger.New().LogErrorWithStack(ctx, err)
+	}
+}
+
+// forfeit はゲーム中のユーザーを死亡させ、通常の死亡と同じように順位とゲームの終了を判定する
+func (gm *GameManager) forfeit(ctx context.Context, roomID, userID string) error {
+	var alive bool
+	err := gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		alive = u.Life > 0
+		u.Life = 0
+		u.Streak = 0
+		return nil
+	})
+	if err != nil || !alive {
+		return err
+	}
+
+	_, err = gm.die(ctx, roomID, userID)
+	return err
+}
+
+// leaveLobby はロビー (または終了後の結果画面) からユーザーを取り除く
+// 誰もいなくなったらルームを閉じる
+func (gm *GameManager) leaveLobby(ctx context.Context, roomID, userID string) error {
+	if err := gm.handoverOwner(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	var (
+		user  *model.User
+		empty bool
+	)
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		user = g.Users[userID]
+		delete(g.Users, userID)
+		empty = len(g.Users) == 0
+		return nil
+	})
+	if err != nil || user == nil {
+		return err
+	}
+
+	if user.ReconnectToken != "" {
+		if err := gm.session.RevokeReconnectToken(ctx, user.ReconnectToken); err != nil {
+			log.Println("failed to revoke reconnect token:", err)
+		}
+	}
+	if err := gm.repo.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if empty {
+		return gm.closeRoom(ctx, roomID)
+	}
+
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeKicked,
+			Payload: &schema.Kicked{
+				UserID: userID,
+				Reason: schema.KickReasonLeave,
+			},
+		},
+		CloseUsers: []string{userID},
+	})
+	if err != nil {
+		return err
+	}
+
+	if game, err := gm.repo.GetGameByID(ctx, roomID); err == nil && game.Status == model.GameStatusFinished {
+		// 投票していないユーザーが抜けると再戦できるようになる
+		return gm.tryRematch(ctx, roomID)
+	}
+
+	if err := gm.publishLobby(ctx, roomID); err != nil {
+		return err
+	}
+
+	return gm.checkAutoStart(ctx, roomID)
+}
+
+// closeRoom は誰もいなくなったロビーを閉じる
+func (gm *GameManager) closeRoom(ctx context.Context, roomID string) error {
+	if err := gm.roomRepo.UpdateRoom(ctx, &model.Room{
+		ID:     roomID,
+		Status: model.RoomStatusFinish,
+	}); err != nil {
+		return err
+	}
+
+	return gm.repo.DeleteGame(ctx, roomID)
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/logger"
+	"github.com/Simo-C3/stego2-server/pkg/uuid"
+)
+
+// SetReady はロビーでの準備完了の状態を変更する
+func (gm *GameManager) SetReady(ctx context.Context, roomID, userID string, ready bool) error {
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		if g.Status != model.GameStatusPending {
+			return model.ErrGameIsStarted
+		}
+		u, ok := g.Users[userID]
+		if !ok {
+			return errors.Errorf("user not found in game: %s", userID)
+		}
+		u.Ready = ready
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = gm.repo.EditUser(ctx, userID, func(u *model.User) error {
+		u.Ready = ready
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := gm.publishLobby(ctx, roomID); err != nil {
+		return err
+	}
+
+	return gm.checkAutoStart(ctx, roomID)
+}
+
+// CancelCountdown はオーナーが自動開始のカウントダウンを止める
+func (gm *GameManager) CancelCountdown(ctx context.Context, roomID, userID string) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if game.BaseRoom.OwnerID != userID {
+		return model.ErrNotOwner
+	}
+
+	return gm.cancelCountdown(ctx, roomID)
+}
+
+// checkAutoStart は全員の準備が整えば自動開始のカウントダウンを始め、崩れればカウントダウンを止める
+func (gm *GameManager) checkAutoStart(ctx context.Context, roomID string) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if !game.BaseRoom.AutoStart || game.Status != model.GameStatusPending {
+		return nil
+	}
+
+	if !game.AllReady() || game.CanStart() != nil {
+		return gm.cancelCountdown(ctx, roomID)
+	}
+
+	id, err := uuid.GenerateUUIDv7()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var started bool
+	err = gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		started = g.CountdownID == ""
+		if started {
+			g.CountdownID = id
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if started {
+		go gm.runCountdown(roomID, id)
+	}
+	return nil
+}
+
+// cancelCountdown は進行中のカウントダウンを止めて全体に通知する
+func (gm *GameManager) cancelCountdown(ctx context.Context, roomID string) error {
+	var cancelled bool
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		cancelled = g.CountdownID != ""
+		g.CountdownID = ""
+		return nil
+	})
+	if err != nil || !cancelled {
+		return err
+	}
+
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeCountdown,
+			Payload: &schema.Countdown{
+				Cancelled: true,
+			},
+		},
+	})
+}
+
+// runCountdown は1秒ごとに残り時間を配信し、0 になったらゲームを開始する
+// game の CountdownID が変わっていたら (止められたら) 終了する
+func (gm *GameManager) runCountdown(roomID, countdownID string) {
+	ctx := context.Background()
+	logger := logger.New()
+
+	for remaining := gm.cfg.AutoStartCountdown; ; remaining-- {
+		game, err := gm.repo.GetGameByID(ctx, roomID)
+		if err != nil || game.CountdownID != countdownID || game.Status != model.GameStatusPending {
+			return
+		}
+
+		if remaining <= 0 {
+			if err := gm.StartGame(ctx, roomID, game.BaseRoom.OwnerID); err != nil {
+				logger.LogErrorWithStack(ctx, err)
+			}
+			return
+		}
+
+		err = gm.publish(ctx, &schema.PublishContent{
+			RoomID: roomID,
+			Payload: schema.Base{
+				Type: schema.TypeCountdown,
+				Payload: &schema.Countdown{
+					Remaining: remaining,
+				},
+			},
+		})
+		if err != nil {
+			logger.LogErrorWithStack(ctx, err)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// publishLobby はロビーの参加者の一覧を全体に配信する
+func (gm *GameManager) publishLobby(ctx context.Context, roomID string) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type:    schema.TypeLobby,
+			Payload: convertToLobby(game),
+		},
+	})
+}
+
+func convertToLobby(game *model.Game) *schema.Lobby {
+	users := make([]*schema.LobbyUser, 0, len(game.Users))
+	for _, u := range game.Users {
+		users = append(users, &schema.LobbyUser{
+			ID:         u.ID,
+			Name:       u.DisplayName,
+			Ready:      u.Ready || u.IsCPU,
+			Team:       u.Team,
+			IsCPU:      u.IsCPU,
+			Connection: convertToConnection(u),
+			Rating:     u.Rating,
+		})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	return &schema.Lobby{
+		OwnerID:    game.BaseRoom.OwnerID,
+		MinUserNum: game.BaseRoom.MinUserNum,
+		MaxUserNum: game.BaseRoom.MaxUserNum,
+		AutoStart:  game.BaseRoom.AutoStart,
+		Users:      users,
+	}
+}
@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/pkg/config"
+	"github.com/Simo-C3/stego2-server/pkg/logger"
+	"github.com/Simo-C3/stego2-server/pkg/uuid"
+)
+
+const (
+	// マッチングしたユーザーが参加するまで席を確保しておく時間
+	matchReserveTTL = 30 * time.Second
+	// マッチングの結果を取りに来るまで保持しておく時間
+	matchResultTTL = time.Minute
+)
+
+// Matchmaker はレーティングの近いユーザーを同じルームに案内する
+// 待ち行列は Redis に置き、ロックを取ったサーバーだけがマッチングを行う
+type Matchmaker struct {
+	repo     repository.MatchmakingRepository
+	roomRepo repository.RoomRepository
+	gameRepo repository.GameRepository
+	otpRepo  repository.OTPRepository
+	rating   repository.RatingRepository
+	cfg      *config.MatchmakingConfig
+	window   *model.MatchWindow
+}
+
+func NewMatchmaker(repo repository.MatchmakingRepository, roomRepo repository.RoomRepository, gameRepo repository.GameRepository, otpRepo repository.OTPRepository, rating repository.RatingRepository, cfg *config.MatchmakingConfig) *Matchmaker {
+	return &Matchmaker{
+		repo:     repo,
+		roomRepo: roomRepo,
+		gameRepo: gameRepo,
+		otpRepo:  otpRepo,
+		rating:   rating,
+		cfg:      cfg,
+		window: &model.MatchWindow{
+			Initial: cfg.InitialWindow,
+			Growth:  cfg.WindowGrowth,
+			Max:     cfg.MaxWindow,
+		},
+	}
+}
+
+// Enqueue はユーザーを待ち行列に追加する
+func (m *Matchmaker) Enqueue(ctx context.Context, userID, name string) error {
+	ratings, err := m.rating.GetRatings(ctx, []string{userID})
+	if err != nil {
+		return err
+	}
+
+	return m.repo.Enqueue(ctx, model.NewMatchTicket(userID, name, ratings[userID].Rating, time.Now()))
+}
+
+// Cancel はユーザーを待ち行列から外す
+func (m *Matchmaker) Cancel(ctx context.Context, userID string) error {
+	_, err := m.repo.Dequeue(ctx, userID)
+	return err
+}
+
+// Wait はマッチングの結果を long-poll で待つ。時間内に見つからなかった場合は nil を返す
+func (m *Matchmaker) Wait(ctx context.Context, userID string) (*model.MatchFound, error) {
+	queued, err := m.repo.Touch(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := m.cfg.PollTimeout
+	if !queued {
+		// 並んでいなくても、マッチング済みなら結果が残っている
+		timeout = time.Second
+	}
+
+	res, err := m.repo.WaitResult(ctx, userID, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil && !queued {
+		return nil, model.ErrNotInQueue
+	}
+
+	return res, nil
+}
+
+// Run は cfg.Interval ごとにマッチングを行う
+func (m *Matchmaker) Run(ctx context.Context) {
+	logger := logger.New()
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := m.repo.Lock(ctx, m.cfg.Interval)
+		if err != nil {
+			logger.LogErrorWithStack(ctx, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if err := m.match(ctx, time.Now()); err != nil {
+			logger.LogErrorWithStack(ctx, err)
+		}
+	}
+}
+
+// match は空きのあるルームを埋めてから、残ったユーザーで新しいルームを作る
+func (m *Matchmaker) match(ctx context.Context, now time.Time) error {
+	tickets, err := m.repo.GetTickets(ctx)
+	if err != nil {
+		return err
+	}
+
+	waiting := make([]*model.MatchTicket, 0, len(tickets))
+	for _, t := range tickets {
+		if t.IsStale(now, m.cfg.StaleTimeout) {
+			if _, err := m.repo.Dequeue(ctx, t.UserID); err != nil {
+				return err
+			}
+			continue
+		}
+		waiting = append(waiting, t)
+	}
+	if len(waiting) == 0 {
+		return nil
+	}
+
+	waiting, err = m.fillRooms(ctx, waiting, now)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range model.GroupMatchTickets(waiting, m.window, now, m.cfg.MinUserNum, m.cfg.MaxUserNum) {
+		if err := m.createRoom(ctx, group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type vacancy struct {
+	game   *model.Game
+	rating float64
+	rated  bool // 人間のユーザーが参加していて rating が平均レーティングか
+	free   int
+}
+
+// vacancies は空きのある待機中のルームを返す
+func (m *Matchmaker) vacancies(ctx context.Context) ([]*vacancy, error) {
+	rooms, err := m.roomRepo.GetPendingRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vacancies := make([]*vacancy, 0, len(rooms))
+	for _, room := range rooms {
+		game, err := m.gameRepo.GetGameByID(ctx, room.ID)
+		if err != nil || game.Status != model.GameStatusPending {
+			continue
+		}
+		rating, rated := game.AverageRating()
+		reserved, err := m.repo.GetReserved(ctx, room.ID)
+		if err != nil {
+			return nil, err
+		}
+		if free := game.BaseRoom.MaxUserNum - len(game.Users) - reserved; free > 0 {
+			vacancies = append(vacancies, &vacancy{game: game, rating: rating, rated: rated, free: free})
+		}
+	}
+
+	return vacancies, nil
+}
+
+// FindRoom は待ち行列に並ばずに、空きのある待機中のルームからレーティングの最も近いルームを選ぶ
+// 誰も参加していないルームは、他に候補がない場合だけ選ぶ。見つからなかった場合は空文字を返す
+func (m *Matchmaker) FindRoom(ctx context.Context, userID string) (string, error) {
+	ratings, err := m.rating.GetRatings(ctx, []string{userID})
+	if err != nil {
+		return "", err
+	}
+	rating := ratings[userID].Rating
+
+	vacancies, err := m.vacancies(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var best *vacancy
+	for _, v := range vacancies {
+		if v.game.IsBanned(userID) {
+			continue
+		}
+		switch {
+		case best == nil, v.rated && !best.rated:
+			best = v
+		case v.rated == best.rated && math.Abs(v.rating-rating) < math.Abs(best.rating-rating):
+			best = v
+		}
+	}
+	if best == nil {
+		return "", nil
+	}
+
+	return best.game.ID, nil
+}
+
+// fillRooms は待機中のルームのうち、平均レーティングが探索幅に収まる最も近いルームにユーザーを入れる
+// まだ誰も参加していないルームは対象にしない
+// 入るルームが見つからなかったユーザーを返す
+func (m *Matchmaker) fillRooms(ctx context.Context, tickets []*model.MatchTicket, now time.Time) ([]*model.MatchTicket, error) {
+	all, err := m.vacancies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vacancies := make([]*vacancy, 0, len(all))
+	for _, v := range all {
+		if v.rated {
+			vacancies = append(vacancies, v)
+		}
+	}
+	if len(vacancies) == 0 {
+		return tickets, nil
+	}
+
+	// 待ち時間の長いユーザーから案内する
+	slices.SortFunc(tickets, func(a, b *model.MatchTicket) int {
+		return cmp.Compare(a.EnqueuedAt, b.EnqueuedAt)
+	})
+
+	remaining := make([]*model.MatchTicket, 0, len(tickets))
+	for _, t := range tickets {
+		var best *vacancy
+		for _, v := range vacancies {
+			if v.free <= 0 || v.game.IsBanned(t.UserID) || !t.Accepts(v.rating, m.window, now) {
+				continue
+			}
+			if best == nil || math.Abs(v.rating-t.Rating) < math.Abs(best.rating-t.Rating) {
+				best = v
+			}
+		}
+		if best == nil {
+			remaining = append(remaining, t)
+			continue
+		}
+
+		if err := m.assign(ctx, best.game.ID, []*model.MatchTicket{t}); err != nil {
+			return nil, err
+		}
+		best.free--
+	}
+
+	return remaining, nil
+}
+
+// createRoom はマッチングしたユーザーで新しいルームを作る
+// 全員が準備完了すれば自動で始まるので、オーナーがいなくても開始できる
+func (m *Matchmaker) createRoom(ctx context.Context, group []*model.MatchTicket) error {
+	id, err := uuid.GenerateUUIDv7()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	owner := group[0]
+	room := model.NewRoom(id, owner.UserID, "Quick Match", owner.Name, m.cfg.MinUserNum, m.cfg.MaxUserNum, false, "pending", 0, 0, model.WinConditionLastStanding, 0, true)
+	if _, err := m.roomRepo.CreateRoom(ctx, room); err != nil {
+		return err
+	}
+
+	return m.assign(ctx, id, group)
+}
+
+// assign はユーザーを待ち行列から外し、ルームに参加するための OTP と一緒に結果を渡す
+// 既にキャンセルしたユーザーには何もしない
+func (m *Matchmaker) assign(ctx context.Context, roomID string, tickets []*model.MatchTicket) error {
+	for _, t := range tickets {
+		ok, err := m.repo.Dequeue(ctx, t.UserID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		otp, err := m.otpRepo.GenerateOTP(ctx, t.UserID, t.Name)
+		if err != nil {
+			return err
+		}
+		if err := m.repo.Reserve(ctx, roomID, t.UserID, matchReserveTTL); err != nil {
+			return err
+		}
+		if err := m.repo.PushResult(ctx, t.UserID, &model.MatchFound{RoomID: roomID, OTP: otp.OTP}, matchResultTTL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+	"log"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// Kick はオーナーがユーザーをロビーから取り除く
+func (gm *GameManager) Kick(ctx context.Context, roomID, userID, targetUserID string) error {
+	return gm.removeUser(ctx, roomID, userID, targetUserID, schema.KickReasonKick)
+}
+
+// Ban はオーナーがユーザーをロビーから取り除き、以後の参加を拒否する
+func (gm *GameManager) Ban(ctx context.Context, roomID, userID, targetUserID string) error {
+	return gm.removeUser(ctx, roomID, userID, targetUserID, schema.KickReasonBan)
+}
+
+func (gm *GameManager) removeUser(ctx context.Context, roomID, userID, targetUserID, reason string) error {
+	var target *model.User
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		if g.BaseRoom.OwnerID != userID {
+			return model.ErrNotOwner
+		}
+		target = g.Users[targetUserID]
+		if reason == schema.KickReasonBan {
+			return g.Ban(targetUserID)
+		}
+		return g.Kick(targetUserID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if target.ReconnectToken != "" {
+		if err := gm.session.RevokeReconnectToken(ctx, target.ReconnectToken); err != nil {
+			log.Println("failed to revoke reconnect token:", err)
+		}
+	}
+	if err := gm.repo.DeleteUser(ctx, targetUserID); err != nil {
+		return err
+	}
+
+	// 取り除いたユーザーには理由を送ってから接続を閉じる
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeKicked,
+			Payload: &schema.Kicked{
+				UserID: targetUserID,
+				Reason: reason,
+			},
+		},
+		CloseUsers: []string{targetUserID},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := gm.publishLobby(ctx, roomID); err != nil {
+		return err
+	}
+
+	// 準備ができていないユーザーがいなくなれば自動開始できる
+	return gm.checkAutoStart(ctx, roomID)
+}
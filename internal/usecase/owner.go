@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// TransferOwner はオーナーが他のユーザーにオーナーを譲る
+func (gm *GameManager) TransferOwner(ctx context.Context, roomID, userID, targetUserID string) error {
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		if g.BaseRoom.OwnerID != userID {
+			return model.ErrNotOwner
+		}
+		return g.TransferOwner(targetUserID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.changeOwner(ctx, roomID, targetUserID)
+}
+
+// handoverOwner はオーナーがロビー (または終了後の結果画面) から抜けたときに、最も長く接続しているユーザーにオーナーを譲る
+// 候補がいない場合は何もしない
+func (gm *GameManager) handoverOwner(ctx context.Context, roomID, userID string) error {
+	var next string
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		next = ""
+		if g.BaseRoom.OwnerID != userID || g.Status == model.GameStatusPlaying {
+			return nil
+		}
+		u := g.NextOwner()
+		if u == nil {
+			return nil
+		}
+		next = u.ID
+		return g.TransferOwner(next)
+	})
+	if err != nil || next == "" {
+		return err
+	}
+
+	return gm.changeOwner(ctx, roomID, next)
+}
+
+// changeOwner は新しいオーナーを MySQL に保存し、全体に通知する
+func (gm *GameManager) changeOwner(ctx context.Context, roomID, ownerID string) error {
+	if err := gm.roomRepo.UpdateRoom(ctx, &model.Room{
+		ID:      roomID,
+		OwnerID: ownerID,
+	}); err != nil {
+		return err
+	}
+
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.ChangeRoomState{
+			Type: schema.TypeChangeRoom,
+			Payload: schema.ChangeRoomStatePayload{
+				UserNum:    len(game.Users),
+				Status:     game.Status.String(),
+				MaxUserNum: game.BaseRoom.MaxUserNum,
+				OwnerID:    game.BaseRoom.OwnerID,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if game.Status != model.GameStatusPending {
+		return nil
+	}
+	return gm.publishLobby(ctx, roomID)
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+)
+
+// updateRatings は試合の順位で CPU 以外のユーザーのレーティングを更新する
+// CPU 以外のユーザーが2人未満の場合は更新しない
+func (gm *GameManager) updateRatings(ctx context.Context, game *model.Game, results []*model.GameResult, now time.Time) (map[string]*model.RatingChange, error) {
+	humans := make([]*model.GameResult, 0, len(results))
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		u, ok := game.Users[r.UserID]
+		if !ok || u.IsCPU {
+			continue
+		}
+		humans = append(humans, r)
+		ids = append(ids, r.UserID)
+	}
+	if len(ids) < 2 {
+		return nil, nil
+	}
+
+	changes, err := gm.rating.EditRatings(ctx, game.MatchID, ids, func(ratings map[string]*model.Rating) []*model.RatingChange {
+		return model.UpdateRatings(ratings, humans, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*model.RatingChange, len(changes))
+	for _, c := range changes {
+		res[c.UserID] = c
+		u := game.Users[c.UserID]
+		if err := gm.board.SetScore(ctx, model.LeaderboardKindRating, u.ID, u.DisplayName, c.After.Rating, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,227 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/domain/repository"
+	"github.com/Simo-C3/stego2-server/internal/domain/service"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+	"github.com/Simo-C3/stego2-server/pkg/config"
+	"github.com/Simo-C3/stego2-server/pkg/logger"
+)
+
+// Reaper は MySQL のルームと Redis のゲームの食い違いや、残ったままのユーザーを定期的に片付ける
+type Reaper struct {
+	pub      service.Publisher
+	roomRepo repository.RoomRepository
+	gameRepo repository.GameRepository
+	cleanup  repository.CleanupRepository
+	cfg      *config.ReaperConfig
+}
+
+func NewReaper(pub service.Publisher, roomRepo repository.RoomRepository, gameRepo repository.GameRepository, cleanup repository.CleanupRepository, cfg *config.ReaperConfig) *Reaper {
+	return &Reaper{
+		pub:      pub,
+		roomRepo: roomRepo,
+		gameRepo: gameRepo,
+		cleanup:  cleanup,
+		cfg:      cfg,
+	}
+}
+
+// Run は cfg.Interval ごとに片付けを行う。ロックを取ったサーバーだけが行う
+func (r *Reaper) Run(ctx context.Context) {
+	logger := logger.New()
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ok, err := r.gameRepo.Lock(ctx, "reaper", r.cfg.Interval)
+		if err != nil {
+			logger.LogErrorWithStack(ctx, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if err := r.reap(ctx, time.Now()); err != nil {
+			logger.LogErrorWithStack(ctx, err)
+		}
+	}
+}
+
+func (r *Reaper) reap(ctx context.Context, now time.Time) error {
+	if err := r.gameRepo.PruneIndex(ctx, now); err != nil {
+		return err
+	}
+
+	cleanups, err := r.reapRooms(ctx, now)
+	if err == nil {
+		var users []*model.Cleanup
+		users, err = r.reapUsers(ctx, now)
+		cleanups = append(cleanups, users...)
+	}
+
+	// 途中で失敗しても、片付けた分は記録する
+	if saveErr := r.cleanup.SaveCleanups(ctx, cleanups); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// reapRooms は終了していないルームをゲームの状態と照らし合わせる
+// ゲームが期限切れになっているか、全員が切断したままのルームは閉じ、状態が違うルームはゲームに合わせる
+func (r *Reaper) reapRooms(ctx context.Context, now time.Time) ([]*model.Cleanup, error) {
+	rooms, err := r.roomRepo.GetActiveRooms(ctx, now.Add(-r.cfg.RoomAge))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		ids = append(ids, room.ID)
+	}
+	games, err := r.gameRepo.GetGames(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanups := make([]*model.Cleanup, 0)
+	for _, room := range rooms {
+		game, ok := games[room.ID]
+		switch {
+		case !ok:
+			if err := r.closeRoom(ctx, room.ID, nil); err != nil {
+				return cleanups, err
+			}
+			cleanups = append(cleanups, model.NewCleanup(model.CleanupKindRoomClosed, room.ID, "game expired", now))
+		case game.IsAbandoned(now, r.cfg.AbandonedAge):
+			if err := r.closeRoom(ctx, room.ID, game); err != nil {
+				return cleanups, err
+			}
+			cleanups = append(cleanups, model.NewCleanup(model.CleanupKindRoomClosed, room.ID, "abandoned", now))
+		case game.Status.String() != room.Status:
+			if err := r.roomRepo.UpdateRoom(ctx, &model.Room{
+				ID:     room.ID,
+				Status: game.Status.String(),
+			}); err != nil {
+				return cleanups, err
+			}
+			detail := fmt.Sprintf("%s -> %s", room.Status, game.Status)
+			cleanups = append(cleanups, model.NewCleanup(model.CleanupKindRoomSynced, room.ID, detail, now))
+		}
+	}
+
+	return cleanups, nil
+}
+
+// closeRoom はルームを終了にして、残っているゲームとユーザーを消す
+// 接続が残っているユーザーと観戦者には、閉じたことを伝えてから接続を閉じる
+func (r *Reaper) closeRoom(ctx context.Context, roomID string, game *model.Game) error {
+	if err := r.roomRepo.UpdateRoom(ctx, &model.Room{
+		ID:     roomID,
+		Status: model.RoomStatusFinish,
+	}); err != nil {
+		return err
+	}
+	if game == nil {
+		return nil
+	}
+
+	// 受け取ったときにゲームが消えていても、CloseUsers には送られる
+	if err := r.publishClosed(ctx, game); err != nil {
+		return err
+	}
+
+	for id := range game.Users {
+		if err := r.gameRepo.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+	}
+	return r.gameRepo.DeleteGame(ctx, roomID)
+}
+
+// publishClosed は全員の接続を閉じる
+// 全員を ExcludeUsers に入れて、CloseUsers として1回だけ送る
+func (r *Reaper) publishClosed(ctx context.Context, game *model.Game) error {
+	ids := make([]string, 0, len(game.Users)+len(game.Spectators))
+	for id := range game.Users {
+		ids = append(ids, id)
+	}
+	ids = append(ids, game.Spectators...)
+
+	data, err := json.Marshal(&schema.PublishContent{
+		RoomID: game.ID,
+		Payload: schema.Base{
+			Type: schema.TypeKicked,
+			Payload: &schema.Kicked{
+				Reason: schema.KickReasonClosed,
+			},
+		},
+		ExcludeUsers: ids,
+		CloseUsers:   ids,
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return r.pub.Publish(ctx, "game", data)
+}
+
+// reapUsers は cfg.UserAge より長く更新されておらず、どのゲームにも参加していないユーザーを消す
+// 調べている間に参加したユーザーは書き込まれているので、消す直前にもう一度更新時刻を確かめる
+func (r *Reaper) reapUsers(ctx context.Context, now time.Time) ([]*model.Cleanup, error) {
+	updatedBefore := now.Add(-r.cfg.UserAge)
+	userIDs, err := r.gameRepo.GetUserIDs(ctx, updatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	gameIDs, err := r.gameRepo.GetGameIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	games, err := r.gameRepo.GetGames(ctx, gameIDs)
+	if err != nil {
+		return nil, err
+	}
+	joined := make(map[string]bool)
+	for _, game := range games {
+		for id := range game.Users {
+			joined[id] = true
+		}
+	}
+
+	cleanups := make([]*model.Cleanup, 0)
+	for _, id := range userIDs {
+		if joined[id] {
+			continue
+		}
+		deleted, err := r.gameRepo.DeleteStaleUser(ctx, id, updatedBefore)
+		if err != nil {
+			return cleanups, err
+		}
+		if !deleted {
+			continue
+		}
+		cleanups = append(cleanups, model.NewCleanup(model.CleanupKindUserRemoved, id, "not in any game", now))
+	}
+
+	return cleanups, nil
+}
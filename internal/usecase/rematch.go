@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"context"
+	"log"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// Rematch は終了したゲームで再戦に投票する
+// オーナーが投票するか、接続している全員が投票したら同じメンバーでロビーに戻す
+func (gm *GameManager) Rematch(ctx context.Context, roomID, userID string) error {
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		return g.VoteRematch(userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.tryRematch(ctx, roomID)
+}
+
+// tryRematch は再戦の条件を満たしていればロビーに戻し、満たしていなければ投票の状況を配信する
+func (gm *GameManager) tryRematch(ctx context.Context, roomID string) error {
+	var (
+		removed []*model.User
+		done    bool
+	)
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		done = g.CanRematch()
+		if done {
+			removed = g.Rematch()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+
+	if !done {
+		if game.Status != model.GameStatusFinished {
+			return nil
+		}
+		return gm.publish(ctx, &schema.PublishContent{
+			RoomID: roomID,
+			Payload: schema.Base{
+				Type: schema.TypeRematchVote,
+				Payload: &schema.RematchVote{
+					UserIDs:  game.RematchVotes,
+					Required: game.RequiredRematchVotes(),
+				},
+			},
+		})
+	}
+
+	for _, u := range removed {
+		if u.ReconnectToken != "" {
+			if err := gm.session.RevokeReconnectToken(ctx, u.ReconnectToken); err != nil {
+				log.Println("failed to revoke reconnect token:", err)
+			}
+		}
+		if err := gm.repo.DeleteUser(ctx, u.ID); err != nil {
+			log.Println("failed to delete user:", err)
+		}
+	}
+
+	// 初期化した状態をユーザーにも反映する (問題はゲームの開始時に配る)
+	for _, u := range game.Users {
+		if err := gm.repo.UpdateUser(ctx, u); err != nil {
+			return err
+		}
+	}
+
+	// 取り除いたオーナーから譲った場合に備えて、オーナーも保存する
+	if err := gm.roomRepo.UpdateRoom(ctx, &model.Room{
+		ID:      roomID,
+		OwnerID: game.BaseRoom.OwnerID,
+		Status:  model.RoomStatusPending,
+	}); err != nil {
+		return err
+	}
+
+	err = gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.ChangeRoomState{
+			Type: schema.TypeChangeRoom,
+			Payload: schema.ChangeRoomStatePayload{
+				UserNum:    len(game.Users),
+				Status:     game.Status.String(),
+				MaxUserNum: game.BaseRoom.MaxUserNum,
+				OwnerID:    game.BaseRoom.OwnerID,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publishLobby(ctx, roomID)
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// RecordInput はクライアントからのメッセージをリプレイに記録する
+func (gm *GameManager) RecordInput(ctx context.Context, roomID, userID string, msg []byte) {
+	gm.record(ctx, roomID, &model.ReplayEvent{
+		At:      time.Now().UnixMilli(),
+		From:    userID,
+		Payload: msg,
+	})
+}
+
+// recordPublish は配信するメッセージをリプレイに記録する
+func (gm *GameManager) recordPublish(ctx context.Context, content *schema.PublishContent) {
+	payload, err := json.Marshal(content.Payload)
+	if err != nil {
+		log.Println("failed to marshal replay event:", err)
+		return
+	}
+
+	gm.record(ctx, content.RoomID, &model.ReplayEvent{
+		At:           time.Now().UnixMilli(),
+		Payload:      payload,
+		IncludeUsers: content.IncludeUsers,
+		ExcludeUsers: content.ExcludeUsers,
+	})
+}
+
+// record はリプレイの記録に失敗してもゲームは止めない
+func (gm *GameManager) record(ctx context.Context, roomID string, event *model.ReplayEvent) {
+	if err := gm.replay.Record(ctx, roomID, event); err != nil {
+		log.Println("failed to record replay event:", err)
+	}
+}
+
+// saveReplay は試合中に記録したメッセージをリプレイとして保存する
+func (gm *GameManager) saveReplay(ctx context.Context, game *model.Game) error {
+	if game.MatchID == "" {
+		return errors.New("match id is empty")
+	}
+
+	return gm.replay.Save(ctx, model.NewReplay(game, time.Now().UnixMilli()), gm.cfg.ReplayTTL)
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// 再接続用のトークンはゲームと同じだけ保持する
+const reconnectTokenTTL = 30 * time.Minute
+
+// Reconnect は切断されたユーザーを同じ状態のままゲームに戻し、全員の状態を送る
+func (gm *GameManager) Reconnect(ctx context.Context, roomID, userID, connID string) error {
+	now := time.Now()
+	var user *model.User
+	err := gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		if !u.CanReconnect(now, gm.cfg.ReconnectGracePeriod) {
+			return model.ErrReconnectExpired
+		}
+		u.ConnID = connID
+		u.DisconnectedAt = 0
+		user = u
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	// 配り損ねていたらここで配る
+	gm.scheduleReveal(game)
+
+	if err := gm.msg.Send(ctx, userID, &schema.Base{
+		Type:    schema.TypeSnapshot,
+		Payload: convertToSnapshot(game),
+	}); err != nil {
+		return err
+	}
+
+	return gm.publishConnection(ctx, roomID, user)
+}
+
+// Disconnect は接続が切れたユーザーを切断中にする
+// 既に別の接続で再接続している場合は何もしない
+func (gm *GameManager) Disconnect(ctx context.Context, roomID, userID, connID string) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	// 取り除かれたユーザーは何もしない
+	if _, ok := game.Users[userID]; !ok {
+		return nil
+	}
+
+	var user *model.User
+	err = gm.editUser(ctx, roomID, userID, func(u *model.User) error {
+		if u.ConnID != connID {
+			return model.ErrStaleConnection
+		}
+		u.DisconnectedAt = time.Now().UnixMilli()
+		user = u
+		return nil
+	})
+	if errors.Is(err, model.ErrStaleConnection) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := gm.publishConnection(ctx, roomID, user); err != nil {
+		return err
+	}
+
+	// ロビーでオーナーが抜けたら、他のユーザーにオーナーを譲る
+	if err := gm.handoverOwner(ctx, roomID, userID); err != nil {
+		return err
+	}
+
+	go gm.watchDisconnect(roomID, userID, user.DisconnectedAt)
+	return nil
+}
+
+func (gm *GameManager) publishConnection(ctx context.Context, roomID string, user *model.User) error {
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type:    schema.TypeChangeOtherUserState,
+			Payload: convertToUserState(user, 0),
+		},
+		ExcludeUsers: []string{user.ID},
+	})
+}
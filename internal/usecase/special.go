@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// specialSequence は入力し終えたときに攻撃の代わりに発動する特殊なシーケンス
+type specialSequence struct {
+	// 出現確率 (%)
+	probability int
+	// 問題のレベルの上乗せ
+	levelBonus int
+	// 効果
+	apply func(gm *GameManager, ctx context.Context, roomID string, user *model.User) error
+}
+
+// specialSequences に登録すると、シーケンスの種類を追加できる
+var specialSequences = map[string]*specialSequence{
+	model.SequenceTypeHeal:         {probability: 5, levelBonus: 3, apply: (*GameManager).heal},
+	model.SequenceTypeShield:       {probability: 3, levelBonus: 3, apply: (*GameManager).shield},
+	model.SequenceTypeReflect:      {probability: 2, levelBonus: 3, apply: (*GameManager).reflect},
+	model.SequenceTypeFreeze:       {probability: 3, levelBonus: 3, apply: (*GameManager).freeze},
+	model.SequenceTypeDoubleDamage: {probability: 3, levelBonus: 3, apply: (*GameManager).doubleDamage},
+}
+
+// specialSequenceOrder は抽選の順序を固定するためのもの
+var specialSequenceOrder = []string{
+	model.SequenceTypeHeal,
+	model.SequenceTypeShield,
+	model.SequenceTypeReflect,
+	model.SequenceTypeFreeze,
+	model.SequenceTypeDoubleDamage,
+}
+
+// rollSequenceType は次のシーケンスの種類を抽選する
+func rollSequenceType() string {
+	n := rand.Intn(100)
+	for _, typ := range specialSequenceOrder {
+		n -= specialSequences[typ].probability
+		if n < 0 {
+			return typ
+		}
+	}
+	return model.SequenceTypeDefault
+}
+
+// heal は自分 (チーム戦では最も難易度の高い味方) の難易度を下げる
+func (gm *GameManager) heal(ctx context.Context, roomID string, user *model.User) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	healer, ok := game.Users[user.ID]
+	if !ok {
+		return nil
+	}
+	target := game.SelectHealTarget(healer)
+
+	var newDifficult int
+	err = gm.editUser(ctx, roomID, target.ID, func(u *model.User) error {
+		u.Difficult -= 200
+		if u.Difficult < 0 {
+			u.Difficult = 0
+		}
+		newDifficult = u.Difficult
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type: schema.TypeChangeWordDifficult,
+			Payload: &schema.ChangeWordDifficult{
+				Difficult: newDifficult,
+				Cause:     "heal",
+			},
+		},
+		IncludeUsers: []string{target.ID},
+	})
+}
+
+// shield は次に受ける攻撃を防ぐ
+func (gm *GameManager) shield(ctx context.Context, roomID string, user *model.User) error {
+	err := gm.editUser(ctx, roomID, user.ID, func(u *model.User) error {
+		u.Shield = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publishEffect(ctx, roomID, schema.TypeShield, &schema.EffectEvent{
+		UserID: user.ID,
+		State:  schema.EffectStateActivated,
+	})
+}
+
+// reflect は次に受ける攻撃を相手に跳ね返す
+func (gm *GameManager) reflect(ctx context.Context, roomID string, user *model.User) error {
+	err := gm.editUser(ctx, roomID, user.ID, func(u *model.User) error {
+		u.Reflect = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publishEffect(ctx, roomID, schema.TypeReflect, &schema.EffectEvent{
+		UserID: user.ID,
+		State:  schema.EffectStateActivated,
+	})
+}
+
+// freeze は攻撃対象の入力を一定時間止める
+func (gm *GameManager) freeze(ctx context.Context, roomID string, user *model.User) error {
+	game, err := gm.repo.GetGameByID(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	attacker, ok := game.Users[user.ID]
+	if !ok {
+		return nil
+	}
+	target := game.SelectTarget(attacker)
+	if target == nil {
+		return nil
+	}
+
+	until := time.Now().Add(model.FreezeDuration).UnixMilli()
+	err = gm.editUser(ctx, roomID, target.ID, func(u *model.User) error {
+		u.FrozenUntil = until
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publishEffect(ctx, roomID, schema.TypeFreeze, &schema.EffectEvent{
+		UserID:   target.ID,
+		State:    schema.EffectStateActivated,
+		From:     user.ID,
+		To:       target.ID,
+		Duration: int(model.FreezeDuration.Milliseconds()),
+	})
+}
+
+// doubleDamage は次の攻撃のダメージを 2 倍にする
+func (gm *GameManager) doubleDamage(ctx context.Context, roomID string, user *model.User) error {
+	err := gm.editUser(ctx, roomID, user.ID, func(u *model.User) error {
+		u.DoubleDamage = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return gm.publishEffect(ctx, roomID, schema.TypeDoubleDamage, &schema.EffectEvent{
+		UserID: user.ID,
+		State:  schema.EffectStateActivated,
+	})
+}
+
+func (gm *GameManager) publishEffect(ctx context.Context, roomID string, typ schema.Type, event *schema.EffectEvent) error {
+	return gm.publish(ctx, &schema.PublishContent{
+		RoomID: roomID,
+		Payload: schema.Base{
+			Type:    typ,
+			Payload: event,
+		},
+	})
+}
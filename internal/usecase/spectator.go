@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Simo-C3/stego2-server/internal/domain/model"
+	"github.com/Simo-C3/stego2-server/internal/schema"
+)
+
+// Spectate は観戦者を登録し、全員の状態を送る
+func (gm *GameManager) Spectate(ctx context.Context, roomID, userID string) error {
+	var game *model.Game
+	err := gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		game = g
+		return g.AddSpectator(userID)
+	})
+	if err != nil {
+		return err
+	}
+	// 配り損ねていたらここで配る
+	gm.scheduleReveal(game)
+
+	return gm.msg.Send(ctx, userID, &schema.Base{
+		Type:    schema.TypeSnapshot,
+		Payload: convertToSnapshot(game),
+	})
+}
+
+// LeaveSpectator は観戦者の登録を解除する
+func (gm *GameManager) LeaveSpectator(ctx context.Context, roomID, userID string) error {
+	return gm.repo.EditGame(ctx, roomID, func(g *model.Game) error {
+		g.RemoveSpectator(userID)
+		return nil
+	})
+}
+
+func convertToSnapshot(game *model.Game) *schema.Snapshot {
+	// 開始の合図の前と、まだ配り終えていないときは問題を見せない
+	started := game.HasStarted(time.Now()) && game.Revealed
+
+	users := make([]*schema.PlayerSnapshot, 0, len(game.Users))
+	for _, u := range game.Users {
+		seqs := make([]*schema.NextSeqEvent, 0, len(u.Sequences))
+		if started {
+			for _, seq := range u.Sequences {
+				seqs = append(seqs, &schema.NextSeqEvent{
+					Value: seq.Value,
+					Type:  seq.Type,
+					Level: seq.Level,
+				})
+			}
+		}
+
+		users = append(users, &schema.PlayerSnapshot{
+			ID:         u.ID,
+			Name:       u.DisplayName,
+			Life:       u.Life,
+			Sequences:  seqs,
+			Pos:        u.Pos,
+			Difficult:  u.Difficult,
+			Streak:     u.Streak,
+			Team:       u.Team,
+			IsCPU:      u.IsCPU,
+			DeadAt:     u.DeadAt,
+			Connection: convertToConnection(u),
+		})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	var startedAt *int64
+	if game.StartAt > 0 {
+		s := int64(game.StartAt)
+		startedAt = &s
+	}
+
+	return &schema.Snapshot{
+		Room: schema.ChangeRoomStatePayload{
+			UserNum:    len(game.Users),
+			Status:     game.Status.String(),
+			StartedAt:  startedAt,
+			StartDelay: model.GameStartDelay,
+			MaxUserNum: game.BaseRoom.MaxUserNum,
+			OwnerID:    game.BaseRoom.OwnerID,
+		},
+		Users: users,
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"cmp"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -54,6 +57,133 @@ func loadEnv(env, def string) string {
 	return cmp.Or(os.Getenv(env), def)
 }
 
+func loadIntEnv(env string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(env))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func loadFloatEnv(env string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(env), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func loadIntsEnv(env string, def []int) []int {
+	v := os.Getenv(env)
+	if v == "" {
+		return def
+	}
+
+	res := make([]int, 0)
+	for _, s := range strings.Split(v, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return def
+		}
+		res = append(res, n)
+	}
+	return res
+}
+
+type GameConfig struct {
+	// 攻撃力の倍率が上がるストリーク数 (昇順)
+	StreakThresholds []int
+	// 切断から再接続できるまでの猶予
+	ReconnectGracePeriod time.Duration
+	// 全員が準備完了してから自動で開始するまでの秒数
+	AutoStartCountdown int
+	// リプレイを保存しておく期間
+	ReplayTTL time.Duration
+}
+
+func NewGameConfig() *GameConfig {
+	return &GameConfig{
+		StreakThresholds:     loadIntsEnv("STREAK_THRESHOLDS", []int{5, 10, 20}),
+		ReconnectGracePeriod: time.Duration(loadIntEnv("RECONNECT_GRACE_PERIOD", 30)) * time.Second,
+		AutoStartCountdown:   loadIntEnv("AUTO_START_COUNTDOWN", 10),
+		ReplayTTL:            time.Duration(loadIntEnv("REPLAY_TTL", 168)) * time.Hour,
+	}
+}
+
+type CPUConfig struct {
+	WPM       int
+	ErrorRate float64
+	// シーケンスのレベル 1 あたりの失敗する確率
+	FailRate float64
+}
+
+func NewCPUConfig() *CPUConfig {
+	return &CPUConfig{
+		WPM:       loadIntEnv("CPU_WPM", 40),
+		ErrorRate: loadFloatEnv("CPU_ERROR_RATE", 0.05),
+		FailRate:  loadFloatEnv("CPU_FAIL_RATE", 0.03),
+	}
+}
+
+type MatchmakingConfig struct {
+	// マッチングを行う間隔
+	Interval time.Duration
+	// マッチングで作るルームの人数
+	MinUserNum int
+	MaxUserNum int
+	// レーティングの探索幅 (Growth は1秒あたりに広がる幅)
+	InitialWindow float64
+	WindowGrowth  float64
+	MaxWindow     float64
+	// long-poll で結果を待つ時間
+	PollTimeout time.Duration
+	// 結果を取りに来なくなったユーザーを待ち行列から外すまでの時間
+	StaleTimeout time.Duration
+}
+
+func NewMatchmakingConfig() *MatchmakingConfig {
+	return &MatchmakingConfig{
+		Interval:      time.Duration(loadIntEnv("MATCHMAKING_INTERVAL", 1)) * time.Second,
+		MinUserNum:    loadIntEnv("MATCHMAKING_MIN_USER_NUM", 2),
+		MaxUserNum:    loadIntEnv("MATCHMAKING_MAX_USER_NUM", 4),
+		InitialWindow: loadFloatEnv("MATCHMAKING_INITIAL_WINDOW", 100),
+		WindowGrowth:  loadFloatEnv("MATCHMAKING_WINDOW_GROWTH", 10),
+		MaxWindow:     loadFloatEnv("MATCHMAKING_MAX_WINDOW", 500),
+		PollTimeout:   time.Duration(loadIntEnv("MATCHMAKING_POLL_TIMEOUT", 25)) * time.Second,
+		StaleTimeout:  time.Duration(loadIntEnv("MATCHMAKING_STALE_TIMEOUT", 60)) * time.Second,
+	}
+}
+
+type ReaperConfig struct {
+	// 片付けを行う間隔
+	Interval time.Duration
+	// 作られてからこの時間が経っていないルームは片付けない
+	RoomAge time.Duration
+	// 全員がこの時間より長く切断しているルームを閉じる
+	AbandonedAge time.Duration
+	// この時間より長く更新されておらず、どのゲームにも参加していないユーザーを消す
+	UserAge time.Duration
+}
+
+func NewReaperConfig() *ReaperConfig {
+	return &ReaperConfig{
+		Interval:     time.Duration(loadIntEnv("REAPER_INTERVAL", 60)) * time.Second,
+		RoomAge:      time.Duration(loadIntEnv("REAPER_ROOM_AGE", 600)) * time.Second,
+		AbandonedAge: time.Duration(loadIntEnv("REAPER_ABANDONED_AGE", 300)) * time.Second,
+		UserAge:      time.Duration(loadIntEnv("REAPER_USER_AGE", 300)) * time.Second,
+	}
+}
+
+type TimerConfig struct {
+	URL string
+}
+
+func NewTimerConfig() *TimerConfig {
+	return &TimerConfig{
+		URL: loadEnv("TIMER_URL", "http://localhost:50000"),
+	}
+}
+
 type FirebaseConfig struct {
 	ServiceAccount string
 }
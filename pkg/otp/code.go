@@ -0,0 +1,22 @@
+package otp
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// 見間違えやすい文字 (0, O, 1, I) を除いている
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateCode は人が読み書きしやすい英数字のコードを生成する
+func GenerateCode(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}